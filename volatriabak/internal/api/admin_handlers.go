@@ -0,0 +1,86 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListSymbols, AddSymbol, RemoveSymbol, and EnqueueBackfill live here rather
+// than handlers.go so the admin-only symbol-universe/backfill surface reads
+// as one unit (mirroring auth_handlers.go's split for the login flow).
+
+// BackfillEnqueuer is implemented by *fetcher.Backfiller. Handler depends
+// on this narrow interface rather than the fetcher package directly, the
+// same way database decouples from stream via WriteHook.
+type BackfillEnqueuer interface {
+	Enqueue(symbol string, jobType string, from, to time.Time) (string, error)
+}
+
+func (h *Handler) ListSymbols(c *gin.Context) {
+	symbols, err := h.db.ListSymbols(false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list symbols"})
+		return
+	}
+	c.JSON(http.StatusOK, symbols)
+}
+
+func (h *Handler) AddSymbol(c *gin.Context) {
+	var request struct {
+		Symbol string `json:"symbol" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if err := h.db.AddSymbol(request.Symbol); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add symbol"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"symbol": request.Symbol})
+}
+
+func (h *Handler) RemoveSymbol(c *gin.Context) {
+	symbol := c.Param("symbol")
+
+	if err := h.db.RemoveSymbol(symbol); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove symbol"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Symbol removed"})
+}
+
+// EnqueueBackfill enqueues a backfill job for an arbitrary symbol/date
+// range on demand, for filling a gap the scheduled daily jobs wouldn't
+// otherwise catch (e.g. backfilling a newly added symbol further back than
+// a year, or re-running a job_type that failed permanently).
+func (h *Handler) EnqueueBackfill(c *gin.Context) {
+	var request struct {
+		Symbol  string    `json:"symbol" binding:"required"`
+		JobType string    `json:"job_type" binding:"required"`
+		From    time.Time `json:"from" binding:"required"`
+		To      time.Time `json:"to" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if h.backfill == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Backfill subsystem not configured"})
+		return
+	}
+
+	jobID, err := h.backfill.Enqueue(request.Symbol, request.JobType, request.From, request.To)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"job_id": jobID})
+}
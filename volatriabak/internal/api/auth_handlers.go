@@ -0,0 +1,170 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klea/volatria/volatria/internal/auth"
+)
+
+// Login, /auth/refresh, /auth/logout, and AuthMiddleware live here rather
+// than handlers.go so the JWT/session flow reads as one unit.
+
+type loginResponse struct {
+	UserID       int    `json:"userID"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (h *Handler) issueSession(userID int) (loginResponse, error) {
+	accessToken, err := h.auth.IssueAccessToken(userID)
+	if err != nil {
+		return loginResponse{}, err
+	}
+
+	refreshToken, refreshHash, err := auth.NewRefreshToken()
+	if err != nil {
+		return loginResponse{}, err
+	}
+
+	if _, err := h.db.CreateSession(userID, refreshHash, h.refreshTTL); err != nil {
+		return loginResponse{}, err
+	}
+
+	return loginResponse{UserID: userID, AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+func (h *Handler) Login(c *gin.Context) {
+	var request struct {
+		Username string `json:"username" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	user, err := h.db.AuthenticateUser(request.Username, request.Password)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	}
+
+	session, err := h.issueSession(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue session"})
+		return
+	}
+
+	if h.limiter != nil {
+		h.limiter.SetUserLimit(strconv.Itoa(user.ID), authenticatedUserRPS, authenticatedUserBurst)
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+func (h *Handler) RefreshToken(c *gin.Context) {
+	var request struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	hash := auth.HashRefreshToken(request.RefreshToken)
+	session, err := h.db.GetSessionByRefreshHash(hash)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		return
+	}
+
+	// Rotate the refresh token on every use: delete the consumed session
+	// and issue a brand new one, so a stolen-but-unused token is worthless
+	// once the legitimate client refreshes.
+	if err := h.db.DeleteSession(session.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate session"})
+		return
+	}
+
+	newSession, err := h.issueSession(session.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newSession)
+}
+
+func (h *Handler) Logout(c *gin.Context) {
+	var request struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	hash := auth.HashRefreshToken(request.RefreshToken)
+	session, err := h.db.GetSessionByRefreshHash(hash)
+	if err != nil {
+		// Already gone/expired: logout is idempotent either way.
+		c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+		return
+	}
+
+	if err := h.db.DeleteSession(session.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+var errMissingBearerToken = errors.New("missing bearer token")
+
+func bearerToken(c *gin.Context) (string, error) {
+	header := c.GetHeader("Authorization")
+	const prefix = "Bearer "
+	if !strings.HasPrefix(header, prefix) {
+		return "", errMissingBearerToken
+	}
+	return strings.TrimPrefix(header, prefix), nil
+}
+
+// AuthMiddleware verifies the signed access token on Authorization: Bearer
+// and sets "userID" (as an int) in the gin context, replacing the old
+// unauthenticated trust of a client-supplied X-User-ID header.
+func (h *Handler) AuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, err := bearerToken(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			c.Abort()
+			return
+		}
+
+		claims, err := h.auth.VerifyAccessToken(token)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			c.Abort()
+			return
+		}
+
+		userID, err := strconv.Atoi(claims.Subject)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			c.Abort()
+			return
+		}
+
+		c.Set("userID", userID)
+		c.Next()
+	}
+}
@@ -0,0 +1,596 @@
+package api
+
+import (
+	"container/heap"
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/klea/volatria/volatria/internal/telemetry"
+	"golang.org/x/sync/singleflight"
+)
+
+// Store is the eviction policy behind a Cache. Implementations only need to
+// be safe for use by a single caller at a time; Cache itself provides the
+// locking.
+type Store interface {
+	Get(key string) (interface{}, bool)
+	// Set inserts or updates key, evicting an entry if the store is full.
+	Set(key string, value interface{})
+	Delete(key string)
+	Len() int
+}
+
+// --- LRU -------------------------------------------------------------------
+
+type lruEntry struct {
+	key   string
+	value interface{}
+}
+
+// LRUStore is a container/list-backed store with O(1) get/set/evict,
+// replacing the old O(n) oldest-timestamp scan.
+type LRUStore struct {
+	capacity int
+	ll       *list.List // front = most recently used
+	items    map[string]*list.Element
+	onEvict  func(key string, value interface{})
+}
+
+func NewLRUStore(capacity int) *LRUStore {
+	return &LRUStore{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// SetEvictHandler installs fn to be called synchronously, within Set,
+// whenever Set evicts an entry to make room for a new key. TinyLFUStore
+// uses this on its window store to run the sketch admission check against
+// main's current victim instead of letting the candidate's value be
+// silently discarded.
+func (s *LRUStore) SetEvictHandler(fn func(key string, value interface{})) {
+	s.onEvict = fn
+}
+
+// PeekVictim returns the key Set would evict next if called with a new
+// key right now, and true; it returns false while the store is below
+// capacity (or unbounded), since Set would not evict anything then.
+func (s *LRUStore) PeekVictim() (string, bool) {
+	if s.capacity <= 0 || s.ll.Len() < s.capacity {
+		return "", false
+	}
+	back := s.ll.Back()
+	if back == nil {
+		return "", false
+	}
+	return back.Value.(*lruEntry).key, true
+}
+
+func (s *LRUStore) Get(key string) (interface{}, bool) {
+	el, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+	s.ll.MoveToFront(el)
+	return el.Value.(*lruEntry).value, true
+}
+
+func (s *LRUStore) Set(key string, value interface{}) {
+	if el, ok := s.items[key]; ok {
+		el.Value.(*lruEntry).value = value
+		s.ll.MoveToFront(el)
+		return
+	}
+
+	el := s.ll.PushFront(&lruEntry{key: key, value: value})
+	s.items[key] = el
+
+	if s.capacity > 0 && s.ll.Len() > s.capacity {
+		oldest := s.ll.Back()
+		if oldest != nil {
+			entry := oldest.Value.(*lruEntry)
+			s.ll.Remove(oldest)
+			delete(s.items, entry.key)
+			if s.onEvict != nil {
+				s.onEvict(entry.key, entry.value)
+			}
+		}
+	}
+}
+
+func (s *LRUStore) Delete(key string) {
+	if el, ok := s.items[key]; ok {
+		s.ll.Remove(el)
+		delete(s.items, key)
+	}
+}
+
+func (s *LRUStore) Len() int {
+	return s.ll.Len()
+}
+
+// --- LFU ---------------------------------------------------------------
+
+type lfuEntry struct {
+	key   string
+	value interface{}
+	freq  int
+	index int
+}
+
+// lfuHeap is a min-heap on freq so the least-frequently-used entry is always
+// at the root and evicts in O(log n).
+type lfuHeap []*lfuEntry
+
+func (h lfuHeap) Len() int            { return len(h) }
+func (h lfuHeap) Less(i, j int) bool  { return h[i].freq < h[j].freq }
+func (h lfuHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i]; h[i].index = i; h[j].index = j }
+func (h *lfuHeap) Push(x interface{}) { e := x.(*lfuEntry); e.index = len(*h); *h = append(*h, e) }
+func (h *lfuHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	*h = old[:n-1]
+	return e
+}
+
+// LFUStore evicts the least-frequently-accessed entry when full.
+type LFUStore struct {
+	capacity int
+	items    map[string]*lfuEntry
+	heap     *lfuHeap
+}
+
+func NewLFUStore(capacity int) *LFUStore {
+	h := &lfuHeap{}
+	heap.Init(h)
+	return &LFUStore{
+		capacity: capacity,
+		items:    make(map[string]*lfuEntry),
+		heap:     h,
+	}
+}
+
+func (s *LFUStore) Get(key string) (interface{}, bool) {
+	e, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+	e.freq++
+	heap.Fix(s.heap, e.index)
+	return e.value, true
+}
+
+func (s *LFUStore) Set(key string, value interface{}) {
+	if e, ok := s.items[key]; ok {
+		e.value = value
+		e.freq++
+		heap.Fix(s.heap, e.index)
+		return
+	}
+
+	e := &lfuEntry{key: key, value: value, freq: 1}
+	heap.Push(s.heap, e)
+	s.items[key] = e
+
+	if s.capacity > 0 && len(s.items) > s.capacity {
+		evicted := heap.Pop(s.heap).(*lfuEntry)
+		delete(s.items, evicted.key)
+	}
+}
+
+func (s *LFUStore) Delete(key string) {
+	if e, ok := s.items[key]; ok {
+		heap.Remove(s.heap, e.index)
+		delete(s.items, key)
+	}
+}
+
+func (s *LFUStore) Len() int {
+	return len(s.items)
+}
+
+// --- TinyLFU -------------------------------------------------------------
+
+// countMinSketch is a small fixed-width approximate frequency counter used
+// as TinyLFU's admission filter: it answers "has this key been seen often
+// enough to be worth admitting" without storing a key-to-count map.
+type countMinSketch struct {
+	width   int
+	depth   int
+	table   [][]uint8
+	seeds   []uint32
+	samples int
+}
+
+func newCountMinSketch(width, depth int) *countMinSketch {
+	table := make([][]uint8, depth)
+	seeds := make([]uint32, depth)
+	for i := range table {
+		table[i] = make([]uint8, width)
+		seeds[i] = uint32(i*2654435761 + 1)
+	}
+	return &countMinSketch{width: width, depth: depth, table: table, seeds: seeds}
+}
+
+func (c *countMinSketch) hash(seed uint32, key string) int {
+	h := seed
+	for i := 0; i < len(key); i++ {
+		h = h*31 + uint32(key[i])
+	}
+	return int(h) % c.width
+}
+
+func (c *countMinSketch) add(key string) {
+	for i := 0; i < c.depth; i++ {
+		idx := c.hash(c.seeds[i], key)
+		if idx < 0 {
+			idx += c.width
+		}
+		if c.table[i][idx] < 255 {
+			c.table[i][idx]++
+		}
+	}
+	c.samples++
+	// Halve all counters periodically so the sketch tracks recent frequency
+	// rather than accumulating forever.
+	if c.samples >= c.width*10 {
+		for i := range c.table {
+			for j := range c.table[i] {
+				c.table[i][j] /= 2
+			}
+		}
+		c.samples = 0
+	}
+}
+
+func (c *countMinSketch) estimate(key string) uint8 {
+	min := uint8(255)
+	for i := 0; i < c.depth; i++ {
+		idx := c.hash(c.seeds[i], key)
+		if idx < 0 {
+			idx += c.width
+		}
+		if c.table[i][idx] < min {
+			min = c.table[i][idx]
+		}
+	}
+	return min
+}
+
+// TinyLFUStore is an admission-filtered LRU: a count-min sketch tracks
+// approximate access frequency, and a candidate key only displaces the
+// current LRU victim if it has been seen at least as often. This protects
+// the cache from one-off scans evicting genuinely hot entries.
+type TinyLFUStore struct {
+	capacity  int
+	window    *LRUStore // small recency window admitted unconditionally
+	main      *LRUStore // SLRU-style main segment guarded by the sketch
+	sketch    *countMinSketch
+	windowCap int
+}
+
+// NewTinyLFUStore builds a TinyLFU store with the given total capacity. A
+// small fraction of it is reserved as an always-admit recency window, per
+// the standard W-TinyLFU design.
+func NewTinyLFUStore(capacity int) *TinyLFUStore {
+	windowCap := capacity / 20 // ~5% window, per W-TinyLFU guidance
+	if windowCap < 1 {
+		windowCap = 1
+	}
+	mainCap := capacity - windowCap
+	if mainCap < 1 {
+		mainCap = 1
+	}
+	s := &TinyLFUStore{
+		capacity:  capacity,
+		window:    NewLRUStore(windowCap),
+		main:      NewLRUStore(mainCap),
+		sketch:    newCountMinSketch(4*capacity+16, 4),
+		windowCap: windowCap,
+	}
+	s.window.SetEvictHandler(s.admit)
+	return s
+}
+
+// admit is the window's eviction handler: a candidate aged out of the
+// window is only promoted into main if the sketch estimates it's been seen
+// at least as often as main's current victim, per the W-TinyLFU admission
+// policy. Losing that comparison just drops the candidate, which is what
+// keeps a one-off scan from evicting genuinely hot entries out of main.
+func (s *TinyLFUStore) admit(key string, value interface{}) {
+	victimKey, full := s.main.PeekVictim()
+	if full && s.sketch.estimate(key) < s.sketch.estimate(victimKey) {
+		return
+	}
+	s.main.Set(key, value)
+}
+
+func (s *TinyLFUStore) Get(key string) (interface{}, bool) {
+	if v, ok := s.window.Get(key); ok {
+		s.sketch.add(key)
+		return v, true
+	}
+	if v, ok := s.main.Get(key); ok {
+		s.sketch.add(key)
+		return v, true
+	}
+	return nil, false
+}
+
+func (s *TinyLFUStore) Set(key string, value interface{}) {
+	s.sketch.add(key)
+
+	if _, ok := s.window.Get(key); ok {
+		s.window.Set(key, value)
+		return
+	}
+	if _, ok := s.main.Get(key); ok {
+		s.main.Set(key, value)
+		return
+	}
+
+	// New key: admit to the window unconditionally. The window's own LRU
+	// eviction drops the coldest window entry once full, which is how a
+	// candidate ages long enough for the sketch to accumulate a frequency
+	// before it would ever compete for a main-segment slot.
+	s.window.Set(key, value)
+}
+
+func (s *TinyLFUStore) Delete(key string) {
+	s.window.Delete(key)
+	s.main.Delete(key)
+}
+
+func (s *TinyLFUStore) Len() int {
+	return s.window.Len() + s.main.Len()
+}
+
+// --- Cache -----------------------------------------------------------------
+
+// RemoteStore is a second-tier cache shared across Volatria replicas (e.g.
+// Redis). It's deliberately a narrow interface so a no-op implementation can
+// stand in when no remote backend is configured.
+type RemoteStore interface {
+	Get(key string) (interface{}, bool)
+	Set(key string, value interface{}, ttl time.Duration)
+}
+
+// noopRemoteStore is used when Cache is built without a RemoteStore,
+// keeping the second-tier code path branch-free.
+type noopRemoteStore struct{}
+
+func (noopRemoteStore) Get(string) (interface{}, bool)         { return nil, false }
+func (noopRemoteStore) Set(string, interface{}, time.Duration) {}
+
+type cacheEntry struct {
+	value     interface{}
+	expiresAt time.Time
+}
+
+// TierMetrics counts hits/misses for one cache tier (local or remote). It
+// also forwards to a telemetry.Metrics when one is bound via bind, so the
+// same counts back both the JSON /metrics handler and Prometheus.
+type TierMetrics struct {
+	mu     sync.Mutex
+	Hits   int64
+	Misses int64
+
+	tier   string
+	export *telemetry.Metrics
+}
+
+// bind wires export so future hits/misses on this tier are also recorded as
+// Prometheus counters labeled with tier (e.g. "local", "remote").
+func (m *TierMetrics) bind(export *telemetry.Metrics, tier string) {
+	m.mu.Lock()
+	m.export, m.tier = export, tier
+	m.mu.Unlock()
+}
+
+func (m *TierMetrics) hit() {
+	m.mu.Lock()
+	m.Hits++
+	export, tier := m.export, m.tier
+	m.mu.Unlock()
+	if export != nil {
+		export.CacheHits.WithLabelValues(tier).Inc()
+	}
+}
+
+func (m *TierMetrics) miss() {
+	m.mu.Lock()
+	m.Misses++
+	export, tier := m.export, m.tier
+	m.mu.Unlock()
+	if export != nil {
+		export.CacheMisses.WithLabelValues(tier).Inc()
+	}
+}
+
+// Snapshot returns a copy of the current counts.
+func (m *TierMetrics) Snapshot() (hits, misses int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Hits, m.Misses
+}
+
+// Cache is a two-tier (local + optional remote) cache with singleflight
+// request coalescing and refresh-ahead renewal. The local tier's eviction
+// policy is pluggable via Store (LRU/LFU/TinyLFU); the remote tier is
+// pluggable via RemoteStore (e.g. Redis) so multiple Volatria replicas can
+// share warm entries.
+type Cache struct {
+	mu           sync.RWMutex
+	store        Store
+	entries      map[string]cacheEntry
+	remote       RemoteStore
+	ttl          time.Duration
+	refreshAhead time.Duration
+	group        singleflight.Group
+	Local        TierMetrics
+	Remote       TierMetrics
+}
+
+// CacheOption configures optional Cache behavior.
+type CacheOption func(*Cache)
+
+// WithRemoteStore attaches a second-tier backend (e.g. Redis) that's
+// consulted on a local miss and populated on a local write.
+func WithRemoteStore(remote RemoteStore) CacheOption {
+	return func(c *Cache) { c.remote = remote }
+}
+
+// WithRefreshAhead enables stale-while-revalidate: entries within
+// refreshAhead of expiry are served immediately but trigger an async reload
+// via the loader passed to GetOrLoad.
+func WithRefreshAhead(refreshAhead time.Duration) CacheOption {
+	return func(c *Cache) { c.refreshAhead = refreshAhead }
+}
+
+// WithMetrics exports this cache's local/remote hit and miss counts as
+// Prometheus counters in addition to the in-process TierMetrics snapshot
+// already used by the JSON /metrics handler.
+func WithMetrics(export *telemetry.Metrics) CacheOption {
+	return func(c *Cache) {
+		c.Local.bind(export, "local")
+		c.Remote.bind(export, "remote")
+	}
+}
+
+// NewCache builds a Cache over store with the given entry TTL. Passing a
+// nil store defaults to an unbounded-capacity LRU (capacity 0 means
+// "don't evict").
+func NewCache(store Store, ttl time.Duration, opts ...CacheOption) *Cache {
+	if store == nil {
+		store = NewLRUStore(0)
+	}
+	c := &Cache{
+		store:   store,
+		entries: make(map[string]cacheEntry),
+		remote:  noopRemoteStore{},
+		ttl:     ttl,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *Cache) expired(key string) bool {
+	entry, ok := c.entries[key]
+	if !ok {
+		return true
+	}
+	return time.Now().After(entry.expiresAt)
+}
+
+// Get returns the cached value for key, consulting the remote tier on a
+// local miss. It does not trigger a refresh; use GetOrLoad for that.
+func (c *Cache) Get(key string) (interface{}, bool) {
+	// Store.Get updates the eviction policy's bookkeeping, so this takes the
+	// write lock rather than a shared read lock.
+	c.mu.Lock()
+	if !c.expired(key) {
+		if v, ok := c.store.Get(key); ok {
+			c.mu.Unlock()
+			c.Local.hit()
+			return v, true
+		}
+	}
+	c.mu.Unlock()
+	c.Local.miss()
+
+	if v, ok := c.remote.Get(key); ok {
+		c.Remote.hit()
+		c.Set(key, v)
+		return v, true
+	}
+	c.Remote.miss()
+	return nil, false
+}
+
+// Set stores value under key with the cache's configured TTL, in both the
+// local store and the remote tier.
+func (c *Cache) Set(key string, value interface{}) {
+	c.mu.Lock()
+	c.store.Set(key, value)
+	c.entries[key] = cacheEntry{value: value, expiresAt: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+
+	c.remote.Set(key, value, c.ttl)
+}
+
+// Delete removes key from the local tier. The remote tier is left as it is:
+// RemoteStore has no delete, so a remote entry stays until its own TTL runs
+// out and can still fill a later local miss.
+func (c *Cache) Delete(key string) {
+	c.mu.Lock()
+	c.store.Delete(key)
+	delete(c.entries, key)
+	c.mu.Unlock()
+}
+
+// Len returns the number of entries currently tracked in the local tier.
+func (c *Cache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.store.Len()
+}
+
+// GetOrLoad returns the cached value for key, coalescing concurrent misses
+// for the same key into a single call to loader (via singleflight) so a
+// stampede on a popular symbol hits the database once instead of once per
+// request. When the cached entry is within refreshAhead of expiring, it is
+// returned immediately and loader is re-run in the background to repopulate
+// the entry before it actually expires.
+func (c *Cache) GetOrLoad(key string, loader func() (interface{}, error)) (interface{}, error) {
+	// The value comes from the store, so an entry its eviction policy has
+	// dropped counts as a miss; Store.Get also needs the write lock.
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok {
+		entry.value, ok = c.store.Get(key)
+		if !ok {
+			delete(c.entries, key)
+		}
+	}
+	c.mu.Unlock()
+
+	if ok && time.Now().Before(entry.expiresAt) {
+		c.Local.hit()
+		if c.refreshAhead > 0 && time.Until(entry.expiresAt) < c.refreshAhead {
+			go c.refresh(key, loader)
+		}
+		return entry.value, nil
+	}
+	c.Local.miss()
+
+	if v, found := c.remote.Get(key); found {
+		c.Remote.hit()
+		c.Set(key, v)
+		return v, nil
+	}
+	c.Remote.miss()
+
+	v, err, _ := c.group.Do(key, func() (interface{}, error) {
+		return loader()
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.Set(key, v)
+	return v, nil
+}
+
+func (c *Cache) refresh(key string, loader func() (interface{}, error)) {
+	c.group.Do(key+":refresh-ahead", func() (interface{}, error) {
+		v, err := loader()
+		if err == nil {
+			c.Set(key, v)
+		}
+		return v, err
+	})
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTinyLFUStoreAdmitsFrequentKeyOverColdScan(t *testing.T) {
+	// windowCap = capacity/20 = 1, mainCap = 19: small enough that a short
+	// cold scan reliably forces an admission decision in main.
+	s := NewTinyLFUStore(20)
+
+	hotKey := "AAPL"
+	for i := 0; i < 50; i++ {
+		s.Set(hotKey, i)
+		s.Get(hotKey) // bump the sketch past the one-off scan's estimate
+	}
+
+	// Fill main to capacity with distinct, lightly-seen keys so it's full
+	// before the scan begins.
+	for i := 0; i < 19; i++ {
+		key := fmt.Sprintf("warm-%d", i)
+		s.Set(key, i)
+		s.Get(key)
+	}
+
+	// A cold scan of keys seen exactly once each should not be able to
+	// evict hotKey out of main, since every scan key's sketch estimate is
+	// lower than hotKey's.
+	for i := 0; i < 200; i++ {
+		s.Set(fmt.Sprintf("scan-%d", i), i)
+	}
+
+	if _, ok := s.main.Get(hotKey); !ok {
+		t.Error("hotKey was evicted from main by a cold one-off scan; admission filter should have rejected the scan keys")
+	}
+}
+
+func TestTinyLFUStoreGetAfterSetRoundTrips(t *testing.T) {
+	s := NewTinyLFUStore(10)
+	s.Set("AAPL", 100.0)
+
+	v, ok := s.Get("AAPL")
+	if !ok {
+		t.Fatal("expected AAPL to be present immediately after Set")
+	}
+	if v.(float64) != 100.0 {
+		t.Errorf("Get(AAPL) = %v, want 100.0", v)
+	}
+}
+
+func TestLRUStorePeekVictimAndEvictHandler(t *testing.T) {
+	s := NewLRUStore(2)
+
+	if _, ok := s.PeekVictim(); ok {
+		t.Fatal("PeekVictim should report false before the store is at capacity")
+	}
+
+	var evictedKey string
+	var evictedValue interface{}
+	s.SetEvictHandler(func(key string, value interface{}) {
+		evictedKey, evictedValue = key, value
+	})
+
+	s.Set("a", 1)
+	s.Set("b", 2)
+
+	victim, ok := s.PeekVictim()
+	if !ok || victim != "a" {
+		t.Fatalf("PeekVictim() = (%q, %v), want (%q, true)", victim, ok, "a")
+	}
+
+	s.Set("c", 3) // evicts "a", the least-recently-used key
+
+	if evictedKey != "a" || evictedValue != 1 {
+		t.Errorf("onEvict called with (%q, %v), want (\"a\", 1)", evictedKey, evictedValue)
+	}
+	if _, ok := s.Get("a"); ok {
+		t.Error("evicted key \"a\" should no longer be present")
+	}
+}
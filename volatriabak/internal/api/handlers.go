@@ -4,116 +4,17 @@ import (
 	"context"
 	"fmt"
 	"net/http"
-	"strconv"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/klea/volatria/volatria/internal/auth"
 	"github.com/klea/volatria/volatria/internal/database"
+	"github.com/klea/volatria/volatria/internal/telemetry"
 )
 
-type CacheEntry struct {
-	Data      interface{}
-	Timestamp time.Time
-}
-
-type CacheMetrics struct {
-	Hits   int64
-	Misses int64
-	mu     sync.Mutex
-}
-
-func (m *CacheMetrics) RecordHit() {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.Hits++
-}
-
-func (m *CacheMetrics) RecordMiss() {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.Misses++
-}
-
-type Cache struct {
-	entries map[string]CacheEntry
-	mu      sync.RWMutex
-	ttl     time.Duration
-	metrics *CacheMetrics
-	maxSize int
-}
-
-func NewCache(ttl time.Duration, maxSize int) *Cache {
-	c := &Cache{
-		entries: make(map[string]CacheEntry),
-		ttl:     ttl,
-		metrics: &CacheMetrics{},
-		maxSize: maxSize,
-	}
-
-	// Start background cleanup
-	go c.cleanup()
-
-	return c
-}
-
-func (c *Cache) cleanup() {
-	ticker := time.NewTicker(1 * time.Minute)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		c.mu.Lock()
-		now := time.Now()
-		for key, entry := range c.entries {
-			if now.Sub(entry.Timestamp) > c.ttl {
-				delete(c.entries, key)
-			}
-		}
-		c.mu.Unlock()
-	}
-}
-
-func (c *Cache) Get(key string) (interface{}, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	entry, exists := c.entries[key]
-	if !exists || time.Since(entry.Timestamp) > c.ttl {
-		c.metrics.RecordMiss()
-		return nil, false
-	}
-	c.metrics.RecordHit()
-	return entry.Data, true
-}
-
-func (c *Cache) Set(key string, data interface{}) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	// Remove oldest entry if cache is full
-	if len(c.entries) >= c.maxSize {
-		var oldestKey string
-		var oldestTime time.Time
-		for key, entry := range c.entries {
-			if oldestTime.IsZero() || entry.Timestamp.Before(oldestTime) {
-				oldestKey = key
-				oldestTime = entry.Timestamp
-			}
-		}
-		delete(c.entries, oldestKey)
-	}
-
-	c.entries[key] = CacheEntry{
-		Data:      data,
-		Timestamp: time.Now(),
-	}
-}
-
-func (c *Cache) Clear() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.entries = make(map[string]CacheEntry)
-}
+// Cache, Store, and the eviction policies (LRU/LFU/TinyLFU) now live in
+// cache.go, along with singleflight coalescing and refresh-ahead renewal.
 
 type StockResponse struct {
 	Symbol    string  `json:"symbol"`
@@ -129,6 +30,7 @@ type HistoricalResponse struct {
 type Price struct {
 	Price     float64 `json:"price"`
 	Timestamp string  `json:"timestamp"`
+	Synthetic bool    `json:"synthetic"`
 }
 
 type ErrorResponse struct {
@@ -137,86 +39,110 @@ type ErrorResponse struct {
 
 var (
 	popularStocks = []string{"AAPL", "MSFT", "GOOGL", "AMZN", "META", "TSLA", "NVDA", "AMD", "INTC", "SQ"}
-	globalCache   = NewCache(5*time.Minute, 100)
 )
 
 type Handler struct {
 	db    *database.Database
 	cache *Cache
-}
 
-func New(db *database.Database) *Handler {
-	return &Handler{
-		db:    db,
-		cache: NewCache(5*time.Minute, 100),
-	}
-}
+	auth       *auth.Manager
+	refreshTTL time.Duration
 
-func (h *Handler) Login(c *gin.Context) {
-	var request struct {
-		Username string `json:"username" binding:"required"`
-		Password string `json:"password" binding:"required"`
-	}
+	// limiter, when set via WithRateLimiter, receives a higher per-user quota
+	// for each principal that authenticates successfully.
+	limiter *ShardedLimiter
 
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
-		return
-	}
+	metrics *telemetry.Metrics
 
-	user, err := h.db.AuthenticateUser(request.Username, request.Password)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
-		return
-	}
+	// backfill, when set via WithBackfiller, lets POST /admin/backfill
+	// enqueue a job without api importing the fetcher package directly.
+	backfill BackfillEnqueuer
+}
+
+// HandlerOption configures optional Handler behavior.
+type HandlerOption func(*Handler)
 
-	c.JSON(http.StatusOK, gin.H{"userID": user.ID})
+// WithRateLimiter wires a ShardedLimiter so Login can grant authenticated
+// users a higher quota than anonymous IPs.
+func WithRateLimiter(limiter *ShardedLimiter) HandlerOption {
+	return func(h *Handler) { h.limiter = limiter }
 }
 
-func (h *Handler) AuthMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		userID := c.GetHeader("X-User-ID")
-		if userID == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-			c.Abort()
-			return
-		}
+// WithCacheMetrics exports the handler's cache hit/miss counts as
+// Prometheus counters alongside the JSON /metrics snapshot GetMetrics
+// already serves.
+func WithCacheMetrics(metrics *telemetry.Metrics) HandlerOption {
+	return func(h *Handler) { h.metrics = metrics }
+}
 
-		c.Set("userID", userID)
-		c.Next()
+// WithBackfiller wires a BackfillEnqueuer so POST /admin/backfill can
+// enqueue jobs against it.
+func WithBackfiller(backfill BackfillEnqueuer) HandlerOption {
+	return func(h *Handler) { h.backfill = backfill }
+}
+
+func New(db *database.Database, authManager *auth.Manager, refreshTTL time.Duration, opts ...HandlerOption) *Handler {
+	h := &Handler{
+		db: db,
+		cache: NewCache(NewTinyLFUStore(200), 5*time.Minute,
+			WithRefreshAhead(30*time.Second)),
+		auth:       authManager,
+		refreshTTL: refreshTTL,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.metrics != nil {
+		WithMetrics(h.metrics)(h.cache)
 	}
+	return h
 }
 
+// authenticatedUserRPS/Burst are the quota granted to a user dimension once
+// they've logged in at least once, well above the anonymous per-IP default.
+const (
+	authenticatedUserRPS   = 50
+	authenticatedUserBurst = 100
+)
+
 func (h *Handler) GetLatestPrice(c *gin.Context) {
 	symbol := c.Param("symbol")
 
-	// Check cache first
-	if entry, exists := h.cache.Get(symbol); exists {
-		c.JSON(http.StatusOK, entry)
-		return
-	}
-
-	price, err := h.db.GetLatestPrice(symbol)
+	// GetOrLoad coalesces concurrent misses for the same symbol into one
+	// DB query instead of letting every request in a stampede hit SQLite.
+	entry, err := h.cache.GetOrLoad(symbol, func() (interface{}, error) {
+		price, err := h.db.GetLatestPrice(c.Request.Context(), symbol)
+		if err != nil {
+			return nil, err
+		}
+		return gin.H{"symbol": symbol, "price": price}, nil
+	})
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Cache the result
-	h.cache.Set(symbol, gin.H{"symbol": symbol, "price": price})
+	c.JSON(http.StatusOK, entry)
+}
 
-	c.JSON(http.StatusOK, gin.H{"symbol": symbol, "price": price})
+// parseSyntheticMode maps the ?synthetic= query value to a
+// database.SyntheticMode, defaulting to including synthetic padding (the
+// historical behavior) for an unset or unrecognized value.
+func parseSyntheticMode(raw string) database.SyntheticMode {
+	switch database.SyntheticMode(raw) {
+	case database.SyntheticExclude:
+		return database.SyntheticExclude
+	case database.SyntheticOnly:
+		return database.SyntheticOnly
+	default:
+		return database.SyntheticInclude
+	}
 }
 
 func (h *Handler) GetHistoricalPrices(c *gin.Context) {
 	symbol := c.Param("symbol")
 	rangeParam := c.DefaultQuery("range", "7d")
-
-	// Check cache first
-	cacheKey := fmt.Sprintf("%s_%s", symbol, rangeParam)
-	if entry, exists := h.cache.Get(cacheKey); exists {
-		c.JSON(http.StatusOK, entry)
-		return
-	}
+	syntheticMode := parseSyntheticMode(c.Query("synthetic"))
 
 	// Parse range parameter
 	end := time.Now()
@@ -233,40 +159,35 @@ func (h *Handler) GetHistoricalPrices(c *gin.Context) {
 		return
 	}
 
-	prices, err := h.db.GetHistoricalPrices(symbol, start, end)
+	cacheKey := fmt.Sprintf("%s_%s_%s", symbol, rangeParam, syntheticMode)
+	entry, err := h.cache.GetOrLoad(cacheKey, func() (interface{}, error) {
+		prices, err := h.db.GetHistoricalPrices(c.Request.Context(), symbol, start, end, syntheticMode)
+		if err != nil {
+			return nil, err
+		}
+
+		formattedPrices := make([]gin.H, len(prices))
+		for i, price := range prices {
+			formattedPrices[i] = gin.H{
+				"symbol":    price.Symbol,
+				"price":     price.Price,
+				"timestamp": price.Timestamp.Format(time.RFC3339),
+				"synthetic": price.Synthetic,
+			}
+		}
+
+		return gin.H{"symbol": symbol, "prices": formattedPrices}, nil
+	})
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Format the response with proper timestamps
-	formattedPrices := make([]gin.H, len(prices))
-	for i, price := range prices {
-		formattedPrices[i] = gin.H{
-			"symbol":    price.Symbol,
-			"price":     price.Price,
-			"timestamp": price.Timestamp.Format(time.RFC3339),
-		}
-	}
-
-	// Cache the result
-	h.cache.Set(cacheKey, gin.H{"symbol": symbol, "prices": formattedPrices})
-
-	c.JSON(http.StatusOK, gin.H{"symbol": symbol, "prices": formattedPrices})
+	c.JSON(http.StatusOK, entry)
 }
 
 func (h *Handler) AddToWatchlist(c *gin.Context) {
-	userIDStr := c.GetHeader("X-User-ID")
-	if userIDStr == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-		return
-	}
-
-	userID, err := strconv.Atoi(userIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
+	userID := c.GetInt("userID")
 
 	var request struct {
 		Symbol string `json:"symbol" binding:"required"`
@@ -319,13 +240,17 @@ func (h *Handler) GetStock(c *gin.Context) {
 
 	symbol := c.Query("symbol")
 
-	// Check cache first
-	if cached, ok := h.cache.Get(symbol); ok {
-		c.JSON(http.StatusOK, cached)
-		return
-	}
-
-	price, err := h.db.GetLatestPrice(symbol)
+	cached, err := h.cache.GetOrLoad(symbol, func() (interface{}, error) {
+		price, err := h.db.GetLatestPrice(ctx, symbol)
+		if err != nil {
+			return nil, err
+		}
+		return StockResponse{
+			Symbol:    symbol,
+			Price:     price,
+			Timestamp: time.Now().Format(time.RFC3339),
+		}, nil
+	})
 	if err != nil {
 		select {
 		case <-ctx.Done():
@@ -336,14 +261,7 @@ func (h *Handler) GetStock(c *gin.Context) {
 		return
 	}
 
-	response := StockResponse{
-		Symbol:    symbol,
-		Price:     price,
-		Timestamp: time.Now().Format(time.RFC3339),
-	}
-
-	h.cache.Set(symbol, response)
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, cached)
 }
 
 func (h *Handler) GetPopularStocks(c *gin.Context) {
@@ -364,26 +282,23 @@ func (h *Handler) GetPopularStocks(c *gin.Context) {
 			go func(i int, symbol string) {
 				defer wg.Done()
 
-				// Check cache first
-				if cached, ok := h.cache.Get(symbol); ok {
-					responses[i] = cached.(StockResponse)
-					return
-				}
-
-				price, err := h.db.GetLatestPrice(symbol)
+				cached, err := h.cache.GetOrLoad(symbol, func() (interface{}, error) {
+					price, err := h.db.GetLatestPrice(ctx, symbol)
+					if err != nil {
+						return nil, err
+					}
+					return StockResponse{
+						Symbol:    symbol,
+						Price:     price,
+						Timestamp: time.Now().Format(time.RFC3339),
+					}, nil
+				})
 				if err != nil {
 					errors[i] = fmt.Errorf("failed to fetch %s: %v", symbol, err)
 					return
 				}
 
-				response := StockResponse{
-					Symbol:    symbol,
-					Price:     price,
-					Timestamp: time.Now().Format(time.RFC3339),
-				}
-
-				h.cache.Set(symbol, response)
-				responses[i] = response
+				responses[i] = cached.(StockResponse)
 			}(i, symbol)
 		}
 	}
@@ -431,7 +346,7 @@ func (h *Handler) GetHistoricalData(c *gin.Context) {
 		end = now
 	}
 
-	stocks, err := h.db.GetHistoricalPrices(symbol, start, end)
+	stocks, err := h.db.GetHistoricalPrices(c.Request.Context(), symbol, start, end, parseSyntheticMode(c.Query("synthetic")))
 	if err != nil {
 		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Historical data not found"})
 		return
@@ -442,6 +357,7 @@ func (h *Handler) GetHistoricalData(c *gin.Context) {
 		prices[i] = Price{
 			Price:     stock.Price,
 			Timestamp: stock.Timestamp.Format(time.RFC3339),
+			Synthetic: stock.Synthetic,
 		}
 	}
 
@@ -455,19 +371,19 @@ func (h *Handler) GetHistoricalData(c *gin.Context) {
 
 // Add metrics endpoint
 func (h *Handler) GetMetrics(c *gin.Context) {
+	localHits, localMisses := h.cache.Local.Snapshot()
+	remoteHits, remoteMisses := h.cache.Remote.Snapshot()
+
+	var hitRate float64
+	if total := localHits + localMisses; total > 0 {
+		hitRate = float64(localHits) / float64(total)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"cache_hits":   h.cache.metrics.Hits,
-		"cache_misses": h.cache.metrics.Misses,
-		"hit_rate":     float64(h.cache.metrics.Hits) / float64(h.cache.metrics.Hits+h.cache.metrics.Misses),
+		"local_cache_hits":    localHits,
+		"local_cache_misses":  localMisses,
+		"remote_cache_hits":   remoteHits,
+		"remote_cache_misses": remoteMisses,
+		"hit_rate":            hitRate,
 	})
 }
-
-// Start a background goroutine to periodically clear the cache
-func init() {
-	go func() {
-		for {
-			time.Sleep(5 * time.Minute)
-			globalCache.Clear()
-		}
-	}()
-}
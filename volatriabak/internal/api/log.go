@@ -0,0 +1,13 @@
+package api
+
+import (
+	"os"
+
+	"github.com/rs/zerolog"
+)
+
+// logger is the api package's structured logger, replacing RequestLogger's
+// old fmt.Printf-based access log. Call sites attach method/path/status/
+// latency/trace_id/span_id fields so a log line can be correlated with the
+// span tracer emits for the same request.
+var logger = zerolog.New(os.Stderr).With().Timestamp().Str("component", "api").Logger()
@@ -1,55 +1,19 @@
 package api
 
 import (
-	"fmt"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/time/rate"
+	"github.com/klea/volatria/volatria/internal/telemetry"
+	"go.opentelemetry.io/otel/trace"
 )
 
-type RateLimiter struct {
-	limiters map[string]*rate.Limiter
-	mu       sync.Mutex
-	rps      int
-}
-
-func NewRateLimiter(rps int) *RateLimiter {
-	return &RateLimiter{
-		limiters: make(map[string]*rate.Limiter),
-		rps:      rps,
-	}
-}
-
-func (rl *RateLimiter) getLimiter(key string) *rate.Limiter {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-
-	if limiter, exists := rl.limiters[key]; exists {
-		return limiter
-	}
-
-	limiter := rate.NewLimiter(rate.Limit(rl.rps), rl.rps)
-	rl.limiters[key] = limiter
-	return limiter
-}
-
-func (rl *RateLimiter) Limit() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// Use IP address as the key for rate limiting
-		ip := c.ClientIP()
-		limiter := rl.getLimiter(ip)
-
-		if !limiter.Allow() {
-			c.JSON(http.StatusTooManyRequests, ErrorResponse{Error: "Rate limit exceeded"})
-			c.Abort()
-			return
-		}
-		c.Next()
-	}
-}
+// Rate limiting lives in ratelimiter.go (ShardedLimiter) so that the
+// sharding, eviction, and peer-sync plumbing don't crowd out the simpler
+// middlewares below.
 
 type CircuitBreaker struct {
 	failures    int
@@ -57,15 +21,39 @@ type CircuitBreaker struct {
 	mu          sync.Mutex
 	threshold   int
 	timeout     time.Duration
+
+	name    string
+	metrics *telemetry.Metrics
 }
 
 func NewCircuitBreaker(threshold int, timeout time.Duration) *CircuitBreaker {
 	return &CircuitBreaker{
 		threshold: threshold,
 		timeout:   timeout,
+		name:      "default",
 	}
 }
 
+// WithMetrics exports this breaker's open/closed state as a Prometheus
+// gauge under name, so an operator dashboard can alert on trips without
+// polling an endpoint.
+func (cb *CircuitBreaker) WithMetrics(metrics *telemetry.Metrics, name string) *CircuitBreaker {
+	cb.metrics = metrics
+	cb.name = name
+	return cb
+}
+
+func (cb *CircuitBreaker) setState(open bool) {
+	if cb.metrics == nil {
+		return
+	}
+	state := 0.0
+	if open {
+		state = 1.0
+	}
+	cb.metrics.CircuitBreakerState.WithLabelValues(cb.name).Set(state)
+}
+
 func (cb *CircuitBreaker) Protect() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		cb.mu.Lock()
@@ -77,6 +65,7 @@ func (cb *CircuitBreaker) Protect() gin.HandlerFunc {
 				return
 			}
 			cb.failures = 0
+			cb.setState(false)
 		}
 		cb.mu.Unlock()
 
@@ -86,15 +75,38 @@ func (cb *CircuitBreaker) Protect() gin.HandlerFunc {
 			cb.mu.Lock()
 			cb.failures++
 			cb.lastFailure = time.Now()
+			tripped := cb.failures >= cb.threshold
 			cb.mu.Unlock()
+			if tripped {
+				cb.setState(true)
+			}
 		}
 	}
 }
 
-type RequestLogger struct {
-	mu sync.Mutex
+// HTTPMetrics records request latency in metrics, labeled by method, route
+// (the matched pattern, not the raw path, to keep cardinality bounded) and
+// status. Mount it ahead of the route handlers, e.g.
+// r.Use(api.HTTPMetrics(metrics)).
+func HTTPMetrics(metrics *telemetry.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.HTTPRequestDuration.WithLabelValues(
+			c.Request.Method,
+			route,
+			strconv.Itoa(c.Writer.Status()),
+		).Observe(time.Since(start).Seconds())
+	}
 }
 
+type RequestLogger struct{}
+
 func NewRequestLogger() *RequestLogger {
 	return &RequestLogger{}
 }
@@ -110,14 +122,23 @@ func (rl *RequestLogger) Log() gin.HandlerFunc {
 		latency := time.Since(start)
 		status := c.Writer.Status()
 
-		rl.mu.Lock()
-		fmt.Printf("[%s] %s %s %d %s\n",
-			time.Now().Format(time.RFC3339),
-			method,
-			path,
-			status,
-			latency,
-		)
-		rl.mu.Unlock()
+		// A valid span context here means otelgin (wired in main.go) already
+		// started a span for this request; folding its IDs into the log line
+		// lets an operator jump from a log entry straight to its trace.
+		spanCtx := trace.SpanContextFromContext(c.Request.Context())
+		traceID, spanID := "-", "-"
+		if spanCtx.IsValid() {
+			traceID = spanCtx.TraceID().String()
+			spanID = spanCtx.SpanID().String()
+		}
+
+		logger.Info().
+			Str("method", method).
+			Str("path", path).
+			Int("status", status).
+			Dur("latency", latency).
+			Str("trace_id", traceID).
+			Str("span_id", spanID).
+			Msg("request")
 	}
 }
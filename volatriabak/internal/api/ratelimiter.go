@@ -0,0 +1,514 @@
+package api
+
+import (
+	"container/list"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klea/volatria/volatria/internal/telemetry"
+)
+
+// Algorithm is the pluggable strategy a ShardedLimiter uses to decide
+// whether a single request against a key should be allowed. TokenBucket
+// and LeakyBucket are the two built-in implementations.
+type Algorithm interface {
+	// Allow reports whether a request at time now is permitted, mutating
+	// internal state as a side effect. Implementations need no locking of
+	// their own: the shard holds its lock around every call.
+	Allow(now time.Time) bool
+	// Depth returns the current bucket depth, for metrics/inspection.
+	Depth() float64
+}
+
+// Behavior is the burst mode recorded in a ShardedLimiterConfig.
+type Behavior int
+
+const (
+	// BehaviorGlobal evaluates every request against the bucket immediately.
+	BehaviorGlobal Behavior = iota
+	// BehaviorBatch is accepted and stored on the limiter, but Allow
+	// evaluates requests exactly as under BehaviorGlobal: no extra burst is
+	// let through before the bucket applies.
+	BehaviorBatch
+)
+
+// TokenBucket is the classic token-bucket algorithm: tokens refill at rate
+// rps per second up to burst, and each request consumes one token.
+type TokenBucket struct {
+	rps    float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket returns a TokenBucket that allows rps requests per second
+// with bursts up to burst.
+func NewTokenBucket(rps float64, burst float64) *TokenBucket {
+	return &TokenBucket{
+		rps:    rps,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+func (tb *TokenBucket) Allow(now time.Time) bool {
+	elapsed := now.Sub(tb.last).Seconds()
+	tb.last = now
+	tb.tokens += elapsed * tb.rps
+	if tb.tokens > tb.burst {
+		tb.tokens = tb.burst
+	}
+	if tb.tokens < 1 {
+		return false
+	}
+	tb.tokens--
+	return true
+}
+
+func (tb *TokenBucket) Depth() float64 {
+	return tb.tokens
+}
+
+// LeakyBucket models the request stream as water poured into a bucket that
+// leaks at a constant rate; a request is rejected once the bucket is full.
+// Unlike TokenBucket it smooths bursts instead of permitting them upfront.
+type LeakyBucket struct {
+	leakRate float64 // requests drained per second
+	capacity float64
+	level    float64
+	last     time.Time
+}
+
+// NewLeakyBucket returns a LeakyBucket that drains at leakRate requests per
+// second with room for capacity queued requests.
+func NewLeakyBucket(leakRate float64, capacity float64) *LeakyBucket {
+	return &LeakyBucket{
+		leakRate: leakRate,
+		capacity: capacity,
+		last:     time.Now(),
+	}
+}
+
+func (lb *LeakyBucket) Allow(now time.Time) bool {
+	elapsed := now.Sub(lb.last).Seconds()
+	lb.last = now
+	lb.level -= elapsed * lb.leakRate
+	if lb.level < 0 {
+		lb.level = 0
+	}
+	if lb.level+1 > lb.capacity {
+		return false
+	}
+	lb.level++
+	return true
+}
+
+func (lb *LeakyBucket) Depth() float64 {
+	return lb.level
+}
+
+// AlgorithmFactory builds a fresh Algorithm for a newly-seen key.
+type AlgorithmFactory func() Algorithm
+
+// limiterEntry is a single key's bucket plus its position in the shard's
+// LRU list, so idle entries can be evicted without a full scan.
+type limiterEntry struct {
+	key     string
+	bucket  Algorithm
+	element *list.Element
+}
+
+// limiterShard owns a slice of the keyspace behind its own RWMutex so that
+// concurrent requests for different keys don't contend on a single lock.
+type limiterShard struct {
+	mu      sync.RWMutex
+	entries map[string]*limiterEntry
+	lru     *list.List // front = most recently used
+	maxIdle int
+}
+
+func newLimiterShard(maxIdle int) *limiterShard {
+	return &limiterShard{
+		entries: make(map[string]*limiterEntry),
+		lru:     list.New(),
+		maxIdle: maxIdle,
+	}
+}
+
+func (s *limiterShard) allow(key string, factory AlgorithmFactory, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, exists := s.entries[key]
+	if !exists {
+		entry = &limiterEntry{key: key, bucket: factory()}
+		entry.element = s.lru.PushFront(entry)
+		s.entries[key] = entry
+
+		if s.maxIdle > 0 && len(s.entries) > s.maxIdle {
+			oldest := s.lru.Back()
+			if oldest != nil {
+				evicted := oldest.Value.(*limiterEntry)
+				s.lru.Remove(oldest)
+				delete(s.entries, evicted.key)
+			}
+		}
+	} else {
+		s.lru.MoveToFront(entry.element)
+	}
+
+	return entry.bucket.Allow(now)
+}
+
+func (s *limiterShard) reapIdle(idleAfter time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for e := s.lru.Back(); e != nil; {
+		entry := e.Value.(*limiterEntry)
+		tb, ok := entry.bucket.(*TokenBucket)
+		var last time.Time
+		if ok {
+			last = tb.last
+		} else if lbk, ok := entry.bucket.(*LeakyBucket); ok {
+			last = lbk.last
+		}
+		prev := e.Prev()
+		if last.Before(idleAfter) {
+			s.lru.Remove(e)
+			delete(s.entries, entry.key)
+		}
+		e = prev
+	}
+}
+
+// PeerSync lets a ShardedLimiter agree on a key's token count with other
+// Volatria instances, so a client can't evade its quota by hitting whichever
+// replica happens to have a cold bucket. Ownership of a key is decided
+// locally by consistentHashRing, not by PeerSync itself, so every
+// implementation agrees on the same owner without having to replicate the
+// ring's hashing. Forward asks the owning peer whether the hit should be
+// allowed; implementations should fall back to local-only behavior (return
+// ok=false) when the peer is unreachable so the caller can decide locally
+// instead of failing the request.
+type PeerSync interface {
+	// Forward asks the owning peer to evaluate key and reports whether the
+	// remote call succeeded (ok) and, if so, whether it was allowed.
+	Forward(key string) (allowed bool, ok bool)
+}
+
+// consistentHashRing picks the owning peer for a key by hashing the key and
+// the peer addresses onto a ring, so adding/removing a peer only reshuffles
+// a small fraction of keys.
+type consistentHashRing struct {
+	peers []string
+	self  string
+}
+
+func newConsistentHashRing(self string, peers []string) *consistentHashRing {
+	sorted := append([]string(nil), peers...)
+	// Order peers by their position on the ring rather than by name.
+	sort.Slice(sorted, func(i, j int) bool { return fnv32(sorted[i]) < fnv32(sorted[j]) })
+	return &consistentHashRing{peers: sorted, self: self}
+}
+
+func (r *consistentHashRing) ownerOf(key string) string {
+	if len(r.peers) == 0 {
+		return r.self
+	}
+	// The owner is the first peer at or after the key's position on the
+	// ring, wrapping around past the last one.
+	h := fnv32(key)
+	idx := sort.Search(len(r.peers), func(i int) bool { return fnv32(r.peers[i]) >= h })
+	if idx == len(r.peers) {
+		idx = 0
+	}
+	return r.peers[idx]
+}
+
+// RejectionCounters tracks over-limit rejections by algorithm and key kind
+// (ip/user), and, when export is set, also counts each one in the
+// Prometheus RateLimiterRejections counter.
+type RejectionCounters struct {
+	tokenBucketIP   int64
+	tokenBucketUser int64
+	leakyBucketIP   int64
+	leakyBucketUser int64
+
+	export *telemetry.Metrics
+}
+
+func (c *RejectionCounters) record(algorithm string, keyKind string) {
+	switch {
+	case algorithm == "token_bucket" && keyKind == "ip":
+		atomic.AddInt64(&c.tokenBucketIP, 1)
+	case algorithm == "token_bucket" && keyKind == "user":
+		atomic.AddInt64(&c.tokenBucketUser, 1)
+	case algorithm == "leaky_bucket" && keyKind == "ip":
+		atomic.AddInt64(&c.leakyBucketIP, 1)
+	case algorithm == "leaky_bucket" && keyKind == "user":
+		atomic.AddInt64(&c.leakyBucketUser, 1)
+	}
+	if c.export != nil {
+		c.export.RateLimiterRejections.WithLabelValues(algorithm, keyKind).Inc()
+	}
+}
+
+// Snapshot returns the current counts as a plain map suitable for JSON.
+func (c *RejectionCounters) Snapshot() map[string]int64 {
+	return map[string]int64{
+		"token_bucket_ip":   atomic.LoadInt64(&c.tokenBucketIP),
+		"token_bucket_user": atomic.LoadInt64(&c.tokenBucketUser),
+		"leaky_bucket_ip":   atomic.LoadInt64(&c.leakyBucketIP),
+		"leaky_bucket_user": atomic.LoadInt64(&c.leakyBucketUser),
+	}
+}
+
+const limiterShardCount = 64
+
+// ShardedLimiterConfig configures a ShardedLimiter.
+type ShardedLimiterConfig struct {
+	// AlgorithmName selects the bucket implementation ("token_bucket" or
+	// "leaky_bucket"); defaults to token_bucket.
+	AlgorithmName string
+	RPS           float64
+	Burst         float64
+	Behavior      Behavior
+	// MaxIdlePerShard bounds how many idle limiters a shard holds before
+	// evicting the least-recently-used one. Zero disables the bound.
+	MaxIdlePerShard int
+	// ReapInterval controls how often idle buckets older than ReapAfter are
+	// dropped by the background reaper. Zero disables the reaper.
+	ReapInterval time.Duration
+	ReapAfter    time.Duration
+	// Peers, when non-empty, enables distributed coordination: requests for
+	// a key owned by a remote peer are forwarded via Sync instead of being
+	// evaluated against the local bucket.
+	Peers []string
+	Self  string
+	Sync  PeerSync
+	// Metrics, when set, exports rejection counts as Prometheus counters in
+	// addition to the in-process RejectionCounters snapshot.
+	Metrics *telemetry.Metrics
+}
+
+// ShardedLimiter is a rate limiter keyed by an arbitrary string (IP, user
+// ID, or route), sharded across limiterShardCount buckets to avoid a single
+// global lock, with LRU eviction of idle keys and an optional background
+// reaper. It supports per-route and per-user overrides on top of a default
+// algorithm/rate, and can defer to a PeerSync implementation so multiple
+// Volatria instances agree on a key's remaining quota.
+type ShardedLimiter struct {
+	shards    [limiterShardCount]*limiterShard
+	factory   AlgorithmFactory
+	algoName  string
+	behavior  Behavior
+	rejection *RejectionCounters
+	sync      PeerSync
+	ring      *consistentHashRing
+	done      chan struct{}
+
+	mu          sync.RWMutex
+	routeLimits map[string]AlgorithmFactory
+	userLimits  map[string]AlgorithmFactory
+}
+
+// NewShardedLimiter builds a ShardedLimiter from cfg, starting the
+// background reaper if ReapInterval is set.
+func NewShardedLimiter(cfg ShardedLimiterConfig) *ShardedLimiter {
+	if cfg.MaxIdlePerShard < 0 {
+		cfg.MaxIdlePerShard = 0
+	}
+
+	factory := tokenBucketFactory(cfg.RPS, cfg.Burst)
+	algoName := "token_bucket"
+	if cfg.AlgorithmName == "leaky_bucket" {
+		factory = leakyBucketFactory(cfg.RPS, cfg.Burst)
+		algoName = "leaky_bucket"
+	}
+
+	l := &ShardedLimiter{
+		factory:     factory,
+		algoName:    algoName,
+		behavior:    cfg.Behavior,
+		rejection:   &RejectionCounters{export: cfg.Metrics},
+		sync:        cfg.Sync,
+		done:        make(chan struct{}),
+		routeLimits: make(map[string]AlgorithmFactory),
+		userLimits:  make(map[string]AlgorithmFactory),
+	}
+
+	if len(cfg.Peers) > 0 {
+		l.ring = newConsistentHashRing(cfg.Self, cfg.Peers)
+	}
+
+	maxIdle := cfg.MaxIdlePerShard
+	for i := range l.shards {
+		l.shards[i] = newLimiterShard(maxIdle)
+	}
+
+	if cfg.ReapInterval > 0 {
+		go l.reap(cfg.ReapInterval, cfg.ReapAfter)
+	}
+
+	return l
+}
+
+func tokenBucketFactory(rps, burst float64) AlgorithmFactory {
+	return func() Algorithm { return NewTokenBucket(rps, burst) }
+}
+
+func leakyBucketFactory(rps, burst float64) AlgorithmFactory {
+	return func() Algorithm { return NewLeakyBucket(rps, burst) }
+}
+
+// SetRouteLimit overrides the default algorithm/rate for requests keyed by
+// a specific route (e.g. "GET /stocks/:symbol").
+func (l *ShardedLimiter) SetRouteLimit(route string, rps, burst float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.algoName == "leaky_bucket" {
+		l.routeLimits[route] = leakyBucketFactory(rps, burst)
+		return
+	}
+	l.routeLimits[route] = tokenBucketFactory(rps, burst)
+}
+
+// SetUserLimit overrides the default algorithm/rate for an authenticated
+// user, so logged-in users can be granted a higher quota than anonymous IPs.
+func (l *ShardedLimiter) SetUserLimit(userID string, rps, burst float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.algoName == "leaky_bucket" {
+		l.userLimits[userID] = leakyBucketFactory(rps, burst)
+		return
+	}
+	l.userLimits[userID] = tokenBucketFactory(rps, burst)
+}
+
+// factoryFor resolves the algorithm/rate for a hit: a route override (keyed
+// by the request path, independent of keyKind) takes precedence, then a
+// per-user override (keyed by key itself, since key is the userID once
+// Limit has resolved keyKind to "user"), falling back to the limiter's
+// default. routeScoped reports whether a route override matched, so the
+// caller can store this hit's bucket separately from key's default bucket
+// instead of letting one overridden route's limiter govern every route.
+func (l *ShardedLimiter) factoryFor(keyKind, key, route string) (factory AlgorithmFactory, routeScoped bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if f, ok := l.routeLimits[route]; ok {
+		return f, true
+	}
+	if keyKind == "user" {
+		if f, ok := l.userLimits[key]; ok {
+			return f, false
+		}
+	}
+	return l.factory, false
+}
+
+func fnv32(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return h.Sum32()
+}
+
+func (l *ShardedLimiter) shardFor(key string) *limiterShard {
+	idx := fnv32(key) % limiterShardCount
+	return l.shards[idx]
+}
+
+// Allow reports whether a request identified by key (scoped by keyKind —
+// "ip" or "user" — with route giving a per-route override regardless of
+// keyKind) should proceed. If peer coordination is enabled and the ring
+// assigns key to a remote instance, the hit is forwarded there first,
+// falling back to the local bucket if the peer can't be reached.
+func (l *ShardedLimiter) Allow(key, keyKind, route string) bool {
+	if l.ring != nil && l.sync != nil && l.ring.ownerOf(key) != l.ring.self {
+		if allowed, ok := l.sync.Forward(key); ok {
+			if !allowed {
+				l.rejection.record(l.algoName, keyKind)
+			}
+			return allowed
+		}
+		// Peer unreachable: fall back to evaluating locally below.
+	}
+
+	factory, routeScoped := l.factoryFor(keyKind, key, route)
+
+	// A route override must not share key's default bucket, or that one
+	// overridden route would end up governing every other route the same
+	// caller hits. Scope its bucket to (route, key) instead; the unscoped
+	// case keeps sharing one bucket across routes for key, same as before.
+	storageKey := key
+	if routeScoped {
+		storageKey = route + "|" + key
+	}
+
+	shard := l.shardFor(storageKey)
+	allowed := shard.allow(storageKey, factory, time.Now())
+	if !allowed {
+		l.rejection.record(l.algoName, keyKind)
+	}
+	return allowed
+}
+
+func (l *ShardedLimiter) reap(interval, idleAfter time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			cutoff := time.Now().Add(-idleAfter)
+			for _, shard := range l.shards {
+				shard.reapIdle(cutoff)
+			}
+		case <-l.done:
+			return
+		}
+	}
+}
+
+// Stop terminates the background reaper. Safe to call even if no reaper
+// was started.
+func (l *ShardedLimiter) Stop() {
+	close(l.done)
+}
+
+// Rejections returns a snapshot of over-limit counters for metrics export.
+func (l *ShardedLimiter) Rejections() map[string]int64 {
+	return l.rejection.Snapshot()
+}
+
+// Limit returns gin middleware that rate-limits by client IP, consulting
+// per-route and per-user overrides when the request matches one.
+func (l *ShardedLimiter) Limit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+
+		key := c.ClientIP()
+		keyKind := "ip"
+		if userID, exists := c.Get("userID"); exists {
+			key = fmt.Sprint(userID)
+			keyKind = "user"
+		}
+
+		if !l.Allow(key, keyKind, route) {
+			c.JSON(http.StatusTooManyRequests, ErrorResponse{Error: "Rate limit exceeded"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
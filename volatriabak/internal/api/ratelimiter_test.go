@@ -0,0 +1,62 @@
+package api
+
+import "testing"
+
+func TestShardedLimiterUserOverrideAppliesToUserID(t *testing.T) {
+	l := NewShardedLimiter(ShardedLimiterConfig{RPS: 100, Burst: 100})
+	defer l.Stop()
+
+	// A tight override keyed by userID "42", mirroring how Limit() calls
+	// Allow once a request is authenticated: key is the userID itself.
+	l.SetUserLimit("42", 0, 1)
+
+	if !l.Allow("42", "user", "/stocks/AAPL") {
+		t.Fatal("first request against a burst-1 override should be allowed")
+	}
+	if l.Allow("42", "user", "/stocks/AAPL") {
+		t.Fatal("second immediate request should be rejected by the burst-1 per-user override, not the 100-burst default")
+	}
+
+	// A different, non-overridden user must still get the generous default.
+	if !l.Allow("99", "user", "/stocks/AAPL") {
+		t.Fatal("user 99 has no override and should fall back to the default limit")
+	}
+	if !l.Allow("99", "user", "/stocks/AAPL") {
+		t.Fatal("user 99's second request should still be within the 100-burst default")
+	}
+}
+
+func TestShardedLimiterRouteOverrideAppliesRegardlessOfKeyKind(t *testing.T) {
+	l := NewShardedLimiter(ShardedLimiterConfig{RPS: 100, Burst: 100})
+	defer l.Stop()
+
+	l.SetRouteLimit("/admin/backfill", 0, 1)
+
+	// An anonymous IP hitting the overridden route is still bound by it.
+	if !l.Allow("1.2.3.4", "ip", "/admin/backfill") {
+		t.Fatal("first request against the route override should be allowed")
+	}
+	if l.Allow("1.2.3.4", "ip", "/admin/backfill") {
+		t.Fatal("second immediate request to the overridden route should be rejected")
+	}
+
+	// The same IP against a different route isn't affected by the override.
+	if !l.Allow("1.2.3.4", "ip", "/stocks") {
+		t.Fatal("a route without an override should use the default limit")
+	}
+}
+
+func TestShardedLimiterDefaultWhenNoOverrideMatches(t *testing.T) {
+	l := NewShardedLimiter(ShardedLimiterConfig{RPS: 100, Burst: 2})
+	defer l.Stop()
+
+	if !l.Allow("5.6.7.8", "ip", "/stocks") {
+		t.Fatal("first request should be allowed under the default burst of 2")
+	}
+	if !l.Allow("5.6.7.8", "ip", "/stocks") {
+		t.Fatal("second request should still be allowed under the default burst of 2")
+	}
+	if l.Allow("5.6.7.8", "ip", "/stocks") {
+		t.Fatal("third immediate request should exceed the default burst of 2")
+	}
+}
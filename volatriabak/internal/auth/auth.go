@@ -0,0 +1,143 @@
+// Package auth issues and verifies the signed access tokens used by the
+// API's authentication middleware, and manages the signing keyring behind
+// them.
+package auth
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Claims is the JWT payload Volatria issues: the subject is the user ID as
+// a string, with the standard registered claims for expiry/issued-at.
+type Claims struct {
+	jwt.RegisteredClaims
+}
+
+// KeyringConfig describes the signing keys a Manager may use. ActiveKid
+// selects which key new tokens are signed with; Keys holds every key a
+// token might still validly reference, so a key can be rotated out
+// gradually instead of invalidating every outstanding token at once.
+type KeyringConfig struct {
+	ActiveKid string
+	Keys      map[string][]byte // kid -> HS256 secret
+}
+
+// Keyring holds the signing/verification keys for a Manager, keyed by kid
+// so tokens signed under a previous key keep validating during rotation.
+type Keyring struct {
+	mu        sync.RWMutex
+	activeKid string
+	keys      map[string][]byte
+}
+
+// NewKeyring builds a Keyring from cfg. It panics if ActiveKid has no
+// corresponding entry in Keys, since that would make token issuance
+// impossible.
+func NewKeyring(cfg KeyringConfig) *Keyring {
+	if _, ok := cfg.Keys[cfg.ActiveKid]; !ok {
+		panic(fmt.Sprintf("auth: active kid %q has no key", cfg.ActiveKid))
+	}
+	keys := make(map[string][]byte, len(cfg.Keys))
+	for kid, key := range cfg.Keys {
+		keys[kid] = key
+	}
+	return &Keyring{activeKid: cfg.ActiveKid, keys: keys}
+}
+
+// Rotate introduces a new active key, keeping the previous keys around so
+// tokens already in flight keep verifying until they expire naturally.
+func (k *Keyring) Rotate(kid string, key []byte) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys[kid] = key
+	k.activeKid = kid
+}
+
+// Active returns the kid and key new tokens should be signed with.
+func (k *Keyring) Active() (kid string, key []byte) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.activeKid, k.keys[k.activeKid]
+}
+
+// Lookup returns the key registered under kid, if any.
+func (k *Keyring) Lookup(kid string) ([]byte, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, ok := k.keys[kid]
+	return key, ok
+}
+
+// Config controls token lifetimes issued by a Manager.
+type Config struct {
+	Issuer    string
+	AccessTTL time.Duration
+}
+
+// Manager issues and verifies HS256 access tokens, signed under whichever
+// key in the Keyring is currently active and verified against any key the
+// Keyring still recognizes (so rotation doesn't break tokens mid-flight).
+type Manager struct {
+	keyring *Keyring
+	config  Config
+}
+
+// NewManager returns a Manager backed by keyring, using cfg for issued
+// token lifetimes.
+func NewManager(keyring *Keyring, cfg Config) *Manager {
+	return &Manager{keyring: keyring, config: cfg}
+}
+
+// IssueAccessToken returns a signed access token for userID.
+func (m *Manager) IssueAccessToken(userID int) (string, error) {
+	kid, key := m.keyring.Active()
+	now := time.Now()
+
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   fmt.Sprint(userID),
+			Issuer:    m.config.Issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(m.config.AccessTTL)),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = kid
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign access token: %v", err)
+	}
+	return signed, nil
+}
+
+// VerifyAccessToken parses and validates tokenString, returning the claims
+// if the signature and expiry check out.
+func (m *Manager) VerifyAccessToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		kid, _ := t.Header["kid"].(string)
+		key, ok := m.keyring.Lookup(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key: %q", kid)
+		}
+		return key, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid access token: %v", err)
+	}
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid access token")
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func testManager(t *testing.T) *Manager {
+	t.Helper()
+	keyring := NewKeyring(KeyringConfig{
+		ActiveKid: "k1",
+		Keys:      map[string][]byte{"k1": []byte("test-secret-k1")},
+	})
+	return NewManager(keyring, Config{Issuer: "volatria-test", AccessTTL: time.Hour})
+}
+
+func TestIssueAndVerifyAccessTokenRoundTrips(t *testing.T) {
+	m := testManager(t)
+
+	token, err := m.IssueAccessToken(42)
+	if err != nil {
+		t.Fatalf("IssueAccessToken: %v", err)
+	}
+
+	claims, err := m.VerifyAccessToken(token)
+	if err != nil {
+		t.Fatalf("VerifyAccessToken: %v", err)
+	}
+	if claims.Subject != "42" {
+		t.Errorf("claims.Subject = %q, want \"42\"", claims.Subject)
+	}
+	if claims.Issuer != "volatria-test" {
+		t.Errorf("claims.Issuer = %q, want \"volatria-test\"", claims.Issuer)
+	}
+}
+
+func TestVerifyAccessTokenRejectsExpiredToken(t *testing.T) {
+	keyring := NewKeyring(KeyringConfig{
+		ActiveKid: "k1",
+		Keys:      map[string][]byte{"k1": []byte("test-secret-k1")},
+	})
+	m := NewManager(keyring, Config{Issuer: "volatria-test", AccessTTL: -time.Minute})
+
+	token, err := m.IssueAccessToken(7)
+	if err != nil {
+		t.Fatalf("IssueAccessToken: %v", err)
+	}
+
+	if _, err := m.VerifyAccessToken(token); err == nil {
+		t.Fatal("expected an already-expired token to fail verification")
+	}
+}
+
+func TestVerifyAccessTokenRejectsTamperedSignature(t *testing.T) {
+	m := testManager(t)
+
+	token, err := m.IssueAccessToken(42)
+	if err != nil {
+		t.Fatalf("IssueAccessToken: %v", err)
+	}
+
+	// Swap the first character of the signature segment: unlike the last
+	// one it carries no base64 padding bits, so any change to it alters the
+	// decoded signature bytes.
+	sigStart := strings.LastIndex(token, ".") + 1
+	replacement := "A"
+	if token[sigStart] == 'A' {
+		replacement = "B"
+	}
+	tampered := token[:sigStart] + replacement + token[sigStart+1:]
+	if tampered == token {
+		t.Fatal("test setup failed to produce a distinct tampered token")
+	}
+	if _, err := m.VerifyAccessToken(tampered); err == nil {
+		t.Fatal("expected a tampered token to fail verification")
+	}
+}
+
+func TestKeyringRotateKeepsOldKeyValidatingInFlightTokens(t *testing.T) {
+	keyring := NewKeyring(KeyringConfig{
+		ActiveKid: "k1",
+		Keys:      map[string][]byte{"k1": []byte("test-secret-k1")},
+	})
+	m := NewManager(keyring, Config{Issuer: "volatria-test", AccessTTL: time.Hour})
+
+	oldToken, err := m.IssueAccessToken(42)
+	if err != nil {
+		t.Fatalf("IssueAccessToken: %v", err)
+	}
+
+	keyring.Rotate("k2", []byte("test-secret-k2"))
+
+	if _, err := m.VerifyAccessToken(oldToken); err != nil {
+		t.Fatalf("token signed under the retired key k1 should still verify, got: %v", err)
+	}
+
+	newToken, err := m.IssueAccessToken(42)
+	if err != nil {
+		t.Fatalf("IssueAccessToken after rotation: %v", err)
+	}
+	if _, err := m.VerifyAccessToken(newToken); err != nil {
+		t.Fatalf("VerifyAccessToken after rotation: %v", err)
+	}
+	if kid, _ := keyring.Active(); kid != "k2" {
+		t.Errorf("Active() kid = %q, want \"k2\" after rotation", kid)
+	}
+}
+
+func TestVerifyAccessTokenRejectsUnknownKid(t *testing.T) {
+	signer := testManager(t)
+	token, err := signer.IssueAccessToken(42)
+	if err != nil {
+		t.Fatalf("IssueAccessToken: %v", err)
+	}
+
+	// A different keyring that has never heard of k1 should refuse to
+	// verify a token signed under it.
+	otherKeyring := NewKeyring(KeyringConfig{
+		ActiveKid: "other",
+		Keys:      map[string][]byte{"other": []byte("unrelated-secret")},
+	})
+	verifier := NewManager(otherKeyring, Config{Issuer: "volatria-test", AccessTTL: time.Hour})
+
+	if _, err := verifier.VerifyAccessToken(token); err == nil {
+		t.Fatal("expected verification to fail against a keyring that doesn't recognize the signing kid")
+	}
+}
+
+func TestNewRefreshTokenHashRoundTrips(t *testing.T) {
+	token, hash, err := NewRefreshToken()
+	if err != nil {
+		t.Fatalf("NewRefreshToken: %v", err)
+	}
+	if token == "" || hash == "" {
+		t.Fatal("NewRefreshToken returned an empty token or hash")
+	}
+	if token == hash {
+		t.Fatal("the raw token and its hash should not be equal")
+	}
+	if got := HashRefreshToken(token); got != hash {
+		t.Errorf("HashRefreshToken(token) = %q, want %q to match what NewRefreshToken returned", got, hash)
+	}
+}
+
+func TestNewRefreshTokenIsUnique(t *testing.T) {
+	_, hash1, err := NewRefreshToken()
+	if err != nil {
+		t.Fatalf("NewRefreshToken: %v", err)
+	}
+	_, hash2, err := NewRefreshToken()
+	if err != nil {
+		t.Fatalf("NewRefreshToken: %v", err)
+	}
+	if hash1 == hash2 {
+		t.Fatal("two generated refresh tokens hashed to the same value")
+	}
+}
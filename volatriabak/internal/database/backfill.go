@@ -0,0 +1,168 @@
+package database
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// maxBackfillAttempts bounds retries before a job is marked permanently
+// failed instead of rescheduled; an operator can still re-enqueue the range
+// by hand via POST /admin/backfill.
+const maxBackfillAttempts = 8
+
+// BackfillJob is one persisted unit of historical ingestion work: fetch
+// job_type's data for symbol over [range_start, range_end). Jobs survive a
+// crash because they, and the high-water mark they advance, live in SQLite
+// rather than in the fetcher's memory.
+type BackfillJob struct {
+	ID          string
+	Symbol      string
+	JobType     string
+	RangeStart  time.Time
+	RangeEnd    time.Time
+	Status      string
+	Attempts    int
+	NextRetryAt time.Time
+	LastError   string
+}
+
+func newBackfillJobID() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate backfill job id: %v", err)
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// EnqueueBackfillJob persists a new pending job for symbol/jobType covering
+// [from, to), due immediately.
+func (d *Database) EnqueueBackfillJob(symbol, jobType string, from, to time.Time) (string, error) {
+	id, err := newBackfillJobID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	_, err = d.db.Exec(
+		`INSERT INTO backfill_jobs (id, symbol, job_type, range_start, range_end, status, attempts, next_retry_at, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, 'pending', 0, ?, ?, ?)`,
+		id, symbol, jobType, from, to, now, now, now,
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to enqueue backfill job for %s: %v", symbol, err)
+	}
+	return id, nil
+}
+
+// ClaimDueBackfillJobs marks up to limit pending jobs whose next_retry_at
+// has elapsed as running and returns them, so two Backfiller loops (or two
+// ticks of the same one) can't both pick up the same job.
+func (d *Database) ClaimDueBackfillJobs(limit int) ([]BackfillJob, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	rows, err := d.db.Query(
+		`SELECT id, symbol, job_type, range_start, range_end, status, attempts, next_retry_at, COALESCE(last_error, '')
+		 FROM backfill_jobs WHERE status = 'pending' AND next_retry_at <= ? ORDER BY next_retry_at LIMIT ?`,
+		now, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []BackfillJob
+	for rows.Next() {
+		var j BackfillJob
+		if err := rows.Scan(&j.ID, &j.Symbol, &j.JobType, &j.RangeStart, &j.RangeEnd, &j.Status, &j.Attempts, &j.NextRetryAt, &j.LastError); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range jobs {
+		if _, err := d.db.Exec("UPDATE backfill_jobs SET status = 'running', updated_at = ? WHERE id = ?", now, jobs[i].ID); err != nil {
+			return nil, err
+		}
+		jobs[i].Status = "running"
+	}
+	return jobs, nil
+}
+
+// CompleteBackfillJob marks a claimed job done.
+func (d *Database) CompleteBackfillJob(id string) error {
+	_, err := d.db.Exec("UPDATE backfill_jobs SET status = 'done', updated_at = ? WHERE id = ?", time.Now(), id)
+	return err
+}
+
+// FailBackfillJob records a job failure, rescheduling it after
+// nextRetryAfter unless attempts has reached maxBackfillAttempts, in which
+// case it's marked permanently failed instead of retried forever.
+func (d *Database) FailBackfillJob(id string, jobErr error, attempts int, nextRetryAfter time.Duration) error {
+	status := "pending"
+	if attempts >= maxBackfillAttempts {
+		status = "failed"
+	}
+	_, err := d.db.Exec(
+		"UPDATE backfill_jobs SET status = ?, attempts = ?, next_retry_at = ?, last_error = ?, updated_at = ? WHERE id = ?",
+		status, attempts, time.Now().Add(nextRetryAfter), jobErr.Error(), time.Now(), id,
+	)
+	return err
+}
+
+// InvalidateBackfillJob immediately marks a job permanently failed,
+// skipping the retry/backoff path, for a condition no amount of backoff
+// would fix (e.g. an unregistered job_type).
+func (d *Database) InvalidateBackfillJob(id string, jobErr error) error {
+	_, err := d.db.Exec(
+		"UPDATE backfill_jobs SET status = 'failed', last_error = ?, updated_at = ? WHERE id = ?",
+		jobErr.Error(), time.Now(), id,
+	)
+	return err
+}
+
+// CountBackfillJobs returns the number of jobs currently in status (e.g.
+// "pending" or "running"), for exporting queue depth.
+func (d *Database) CountBackfillJobs(status string) (int, error) {
+	var n int
+	err := d.db.QueryRow("SELECT COUNT(*) FROM backfill_jobs WHERE status = ?", status).Scan(&n)
+	return n, err
+}
+
+// GetBackfillWatermark returns the high-water mark recorded for
+// symbol/jobType, if a backfill run has ever completed for it.
+func (d *Database) GetBackfillWatermark(symbol, jobType string) (time.Time, bool, error) {
+	var hwm time.Time
+	err := d.db.QueryRow(
+		"SELECT high_water_mark FROM backfill_watermarks WHERE symbol = ? AND job_type = ?",
+		symbol, jobType,
+	).Scan(&hwm)
+	if err == sql.ErrNoRows {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return hwm, true, nil
+}
+
+// SetBackfillWatermark advances the high-water mark for symbol/jobType to
+// through, so the next Backfiller run for it only requests what's still
+// missing instead of re-pulling the whole range.
+func (d *Database) SetBackfillWatermark(symbol, jobType string, through time.Time) error {
+	_, err := d.db.Exec(
+		`INSERT INTO backfill_watermarks (symbol, job_type, high_water_mark) VALUES (?, ?, ?)
+		 ON CONFLICT(symbol, job_type) DO UPDATE SET high_water_mark = excluded.high_water_mark
+		 WHERE excluded.high_water_mark > backfill_watermarks.high_water_mark`,
+		symbol, jobType, through,
+	)
+	return err
+}
@@ -0,0 +1,139 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestDatabase opens an isolated in-memory database for a single test.
+// A unique shared-cache name per call keeps parallel tests from seeing each
+// other's data, and a single-connection pool keeps every query on the same
+// underlying SQLite connection, since a plain ":memory:" DSN would otherwise
+// hand out a fresh (and schema-less) database per pooled connection.
+func newTestDatabase(t *testing.T) *Database {
+	t.Helper()
+	dsn := "file:" + t.Name() + "?mode=memory&cache=shared&_journal=WAL&_timeout=5000&_busy_timeout=5000"
+	d, err := newWithDSN(dsn, 1)
+	if err != nil {
+		t.Fatalf("newWithDSN failed: %v", err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d
+}
+
+func TestClaimDueBackfillJobsSkipsNotYetDueJobs(t *testing.T) {
+	d := newTestDatabase(t)
+
+	pastDue, err := d.EnqueueBackfillJob("AAPL", "historical", time.Now().Add(-time.Hour), time.Now())
+	if err != nil {
+		t.Fatalf("EnqueueBackfillJob: %v", err)
+	}
+
+	notYetDue, err := d.EnqueueBackfillJob("MSFT", "historical", time.Now().Add(-time.Hour), time.Now())
+	if err != nil {
+		t.Fatalf("EnqueueBackfillJob: %v", err)
+	}
+	if _, err := d.db.Exec("UPDATE backfill_jobs SET next_retry_at = ? WHERE id = ?", time.Now().Add(time.Hour), notYetDue); err != nil {
+		t.Fatalf("failed to push MSFT's job into the future: %v", err)
+	}
+
+	jobs, err := d.ClaimDueBackfillJobs(10)
+	if err != nil {
+		t.Fatalf("ClaimDueBackfillJobs: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != pastDue {
+		t.Fatalf("ClaimDueBackfillJobs returned %+v, want only the past-due AAPL job", jobs)
+	}
+	if jobs[0].Status != "running" {
+		t.Errorf("claimed job status = %q, want the pre-claim \"pending\" snapshot to have been marked \"running\" in storage", jobs[0].Status)
+	}
+}
+
+func TestClaimDueBackfillJobsDoesNotReclaimRunningJob(t *testing.T) {
+	d := newTestDatabase(t)
+
+	if _, err := d.EnqueueBackfillJob("AAPL", "historical", time.Now().Add(-time.Hour), time.Now()); err != nil {
+		t.Fatalf("EnqueueBackfillJob: %v", err)
+	}
+
+	first, err := d.ClaimDueBackfillJobs(10)
+	if err != nil {
+		t.Fatalf("ClaimDueBackfillJobs (first): %v", err)
+	}
+	if len(first) != 1 {
+		t.Fatalf("first claim returned %d jobs, want 1", len(first))
+	}
+
+	second, err := d.ClaimDueBackfillJobs(10)
+	if err != nil {
+		t.Fatalf("ClaimDueBackfillJobs (second): %v", err)
+	}
+	if len(second) != 0 {
+		t.Fatalf("second claim returned %d jobs, want 0 since the job is already running", len(second))
+	}
+}
+
+func TestFailBackfillJobBacksOffUntilMaxAttemptsThenFails(t *testing.T) {
+	d := newTestDatabase(t)
+
+	id, err := d.EnqueueBackfillJob("AAPL", "historical", time.Now().Add(-time.Hour), time.Now())
+	if err != nil {
+		t.Fatalf("EnqueueBackfillJob: %v", err)
+	}
+
+	for attempt := 1; attempt < maxBackfillAttempts; attempt++ {
+		before := time.Now()
+		if err := d.FailBackfillJob(id, errors.New("provider timeout"), attempt, time.Duration(attempt)*time.Minute); err != nil {
+			t.Fatalf("FailBackfillJob(attempt=%d): %v", attempt, err)
+		}
+
+		var status string
+		var nextRetryAt time.Time
+		if err := d.db.QueryRow("SELECT status, next_retry_at FROM backfill_jobs WHERE id = ?", id).Scan(&status, &nextRetryAt); err != nil {
+			t.Fatalf("querying job after attempt %d: %v", attempt, err)
+		}
+		if status != "pending" {
+			t.Fatalf("after attempt %d, status = %q, want \"pending\" (attempts %d < max %d)", attempt, status, attempt, maxBackfillAttempts)
+		}
+		if !nextRetryAt.After(before) {
+			t.Fatalf("after attempt %d, next_retry_at = %v did not move into the future relative to %v", attempt, nextRetryAt, before)
+		}
+	}
+
+	if err := d.FailBackfillJob(id, errors.New("provider timeout"), maxBackfillAttempts, time.Minute); err != nil {
+		t.Fatalf("FailBackfillJob(attempt=max): %v", err)
+	}
+	var status string
+	if err := d.db.QueryRow("SELECT status FROM backfill_jobs WHERE id = ?", id).Scan(&status); err != nil {
+		t.Fatalf("querying job after final attempt: %v", err)
+	}
+	if status != "failed" {
+		t.Errorf("status after reaching maxBackfillAttempts = %q, want \"failed\"", status)
+	}
+}
+
+func TestSetBackfillWatermarkOnlyAdvances(t *testing.T) {
+	d := newTestDatabase(t)
+
+	later := time.Now().Truncate(time.Second)
+	earlier := later.Add(-time.Hour)
+
+	if err := d.SetBackfillWatermark("AAPL", "historical", later); err != nil {
+		t.Fatalf("SetBackfillWatermark: %v", err)
+	}
+	if err := d.SetBackfillWatermark("AAPL", "historical", earlier); err != nil {
+		t.Fatalf("SetBackfillWatermark (regression attempt): %v", err)
+	}
+
+	hwm, ok, err := d.GetBackfillWatermark("AAPL", "historical")
+	if err != nil {
+		t.Fatalf("GetBackfillWatermark: %v", err)
+	}
+	if !ok {
+		t.Fatal("expected a watermark to be recorded")
+	}
+	if !hwm.Equal(later) {
+		t.Errorf("watermark = %v, want it to stay at %v instead of regressing to %v", hwm, later, earlier)
+	}
+}
@@ -2,20 +2,39 @@ package database
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
-	"math/rand"
+	"hash/fnv"
+	"math"
+	mathrand "math/rand"
 	"sync"
 	"time"
 
+	"github.com/klea/volatria/volatria/internal/telemetry"
 	_ "github.com/mattn/go-sqlite3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// tracer emits spans around the query methods that already thread a
+// context.Context through to database/sql (GetLatestPrice,
+// GetHistoricalPrices), so a traceparent on the inbound HTTP request shows
+// up as a child span here instead of the trace stopping at the handler.
+var tracer = otel.Tracer("volatria/database")
+
 type Stock struct {
 	Symbol    string
 	Price     float64
 	Timestamp time.Time
+	// Synthetic marks a point fabricated to pad out a sparse historical
+	// range (see generateGBMSeries) rather than read from a stored row, so
+	// callers doing real analytics can filter it out.
+	Synthetic bool
 }
 
 type User struct {
@@ -24,6 +43,14 @@ type User struct {
 	Password string
 }
 
+type Session struct {
+	ID               string
+	UserID           int
+	RefreshTokenHash string
+	CreatedAt        time.Time
+	ExpiresAt        time.Time
+}
+
 type DatabaseMetrics struct {
 	ActiveConnections int64
 	IdleConnections   int64
@@ -31,27 +58,91 @@ type DatabaseMetrics struct {
 	mu                sync.Mutex
 }
 
+// WriteHook is invoked after a new stock price row is successfully stored,
+// so a caller (e.g. the stream package's Hub) can fan the price out to
+// subscribers without database importing stream.
+type WriteHook func(symbol string, price float64, timestamp time.Time)
+
 type Database struct {
-	db      *sql.DB
-	mu      sync.RWMutex
-	pool    chan struct{}
-	closed  bool
-	metrics *DatabaseMetrics
-	ctx     context.Context
-	cancel  context.CancelFunc
+	db        *sql.DB
+	mu        sync.RWMutex
+	pool      chan struct{}
+	closed    bool
+	metrics   *DatabaseMetrics
+	ctx       context.Context
+	cancel    context.CancelFunc
+	writeHook WriteHook
+
+	metricsExport *telemetry.Metrics
+}
+
+// SetMetrics wires m so query durations and connection-pool stats are also
+// exported as Prometheus collectors, alongside the existing DatabaseMetrics
+// snapshot returned by GetMetrics. Only one Metrics is supported; calling
+// this again replaces the previous one.
+func (d *Database) SetMetrics(m *telemetry.Metrics) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.metricsExport = m
 }
 
+// observeQuery records latency since start under query in both the legacy
+// DatabaseMetrics.QueryDuration field and, if SetMetrics was called, the
+// Prometheus DBQueryDuration histogram.
+func (d *Database) observeQuery(query string, start time.Time) {
+	elapsed := time.Since(start)
+
+	d.metrics.mu.Lock()
+	d.metrics.QueryDuration = elapsed.Milliseconds()
+	d.metrics.mu.Unlock()
+
+	d.mu.RLock()
+	export := d.metricsExport
+	d.mu.RUnlock()
+	if export != nil {
+		export.DBQueryDuration.WithLabelValues(query).Observe(elapsed.Seconds())
+	}
+}
+
+// SetWriteHook registers hook to be called after every successful
+// StoreStock/StoreStockWithTimestamp. Only one hook is supported; calling
+// this again replaces the previous one.
+func (d *Database) SetWriteHook(hook WriteHook) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.writeHook = hook
+}
+
+func (d *Database) notifyWrite(symbol string, price float64, timestamp time.Time) {
+	d.mu.RLock()
+	hook := d.writeHook
+	d.mu.RUnlock()
+	if hook != nil {
+		hook(symbol, price, timestamp)
+	}
+}
+
+// New opens (or creates) the on-disk SQLite database this binary uses in
+// production.
 func New() (*Database, error) {
+	return newWithDSN("./volatria.db?_journal=WAL&_timeout=5000&_busy_timeout=5000", 25)
+}
+
+// newWithDSN is New's implementation, factored out so tests can point it at
+// an in-memory database (e.g. "file::memory:?cache=shared...") with a
+// single-connection pool instead of the on-disk file, without duplicating
+// the schema/seed setup.
+func newWithDSN(dsn string, maxOpenConns int) (*Database, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	db, err := sql.Open("sqlite3", "./volatria.db?_journal=WAL&_timeout=5000&_busy_timeout=5000")
+	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
 		cancel()
 		return nil, err
 	}
 
 	// Set connection pool settings
-	db.SetMaxOpenConns(25)
+	db.SetMaxOpenConns(maxOpenConns)
 	db.SetMaxIdleConns(5)
 	db.SetConnMaxLifetime(time.Hour)
 	db.SetConnMaxIdleTime(30 * time.Minute)
@@ -85,9 +176,47 @@ func New() (*Database, error) {
 			FOREIGN KEY (user_id) REFERENCES users(id)
 		) WITHOUT ROWID;
 
+		CREATE TABLE IF NOT EXISTS sessions (
+			id TEXT PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			refresh_token_hash TEXT UNIQUE NOT NULL,
+			created_at DATETIME NOT NULL,
+			expires_at DATETIME NOT NULL,
+			FOREIGN KEY (user_id) REFERENCES users(id)
+		);
+
+		CREATE TABLE IF NOT EXISTS symbols (
+			symbol TEXT PRIMARY KEY,
+			active INTEGER NOT NULL DEFAULT 1,
+			added_at DATETIME NOT NULL
+		) WITHOUT ROWID;
+
+		CREATE TABLE IF NOT EXISTS backfill_jobs (
+			id TEXT PRIMARY KEY,
+			symbol TEXT NOT NULL,
+			job_type TEXT NOT NULL,
+			range_start DATETIME NOT NULL,
+			range_end DATETIME NOT NULL,
+			status TEXT NOT NULL DEFAULT 'pending',
+			attempts INTEGER NOT NULL DEFAULT 0,
+			next_retry_at DATETIME NOT NULL,
+			last_error TEXT,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS backfill_watermarks (
+			symbol TEXT NOT NULL,
+			job_type TEXT NOT NULL,
+			high_water_mark DATETIME NOT NULL,
+			PRIMARY KEY (symbol, job_type)
+		) WITHOUT ROWID;
+
 		CREATE INDEX IF NOT EXISTS idx_stocks_symbol_timestamp ON stocks(symbol, timestamp);
 		CREATE INDEX IF NOT EXISTS idx_watchlist_user_id ON watchlist(user_id);
 		CREATE INDEX IF NOT EXISTS idx_stocks_timestamp ON stocks(timestamp);
+		CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+		CREATE INDEX IF NOT EXISTS idx_backfill_jobs_status_retry ON backfill_jobs(status, next_retry_at);
 	`)
 	if err != nil {
 		cancel()
@@ -96,7 +225,7 @@ func New() (*Database, error) {
 
 	// Create default user if it doesn't exist
 	_, err = db.Exec(`
-		INSERT OR IGNORE INTO users (username, password) 
+		INSERT OR IGNORE INTO users (username, password)
 		VALUES (?, ?)
 	`, "Shandris", hashPassword("ShandrisStocks"))
 	if err != nil {
@@ -104,9 +233,23 @@ func New() (*Database, error) {
 		return nil, err
 	}
 
+	// Seed the symbol universe from the set the fetcher used to hard-code,
+	// so behavior is unchanged out of the box; operators manage it from
+	// here on via AddSymbol/RemoveSymbol (see the /admin/symbols CRUD
+	// routes in the api package).
+	for _, symbol := range defaultSymbolUniverse {
+		if _, err := db.Exec(
+			"INSERT OR IGNORE INTO symbols (symbol, active, added_at) VALUES (?, 1, ?)",
+			symbol, time.Now(),
+		); err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+
 	d := &Database{
 		db:      db,
-		pool:    make(chan struct{}, 25),
+		pool:    make(chan struct{}, maxOpenConns),
 		metrics: &DatabaseMetrics{},
 		ctx:     ctx,
 		cancel:  cancel,
@@ -132,6 +275,14 @@ func (d *Database) collectMetrics() {
 			d.metrics.ActiveConnections = int64(stats.InUse)
 			d.metrics.IdleConnections = int64(stats.Idle)
 			d.metrics.mu.Unlock()
+
+			d.mu.RLock()
+			export := d.metricsExport
+			d.mu.RUnlock()
+			if export != nil {
+				export.DBConnectionsInUse.Set(float64(stats.InUse))
+				export.DBConnectionsIdle.Set(float64(stats.Idle))
+			}
 		}
 	}
 }
@@ -183,25 +334,94 @@ func (d *Database) AuthenticateUser(username, password string) (*User, error) {
 		return nil, err
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
-	if err != nil {
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
 		return nil, err
 	}
 
+	// Transparently upgrade the stored hash if bcrypt.DefaultCost has been
+	// raised since it was written, so cost bumps roll out without forcing
+	// a password reset.
+	if cost, err := bcrypt.Cost([]byte(user.Password)); err == nil && cost < bcrypt.DefaultCost {
+		if rehashed := hashPassword(password); rehashed != "" {
+			if _, err := d.db.Exec("UPDATE users SET password = ? WHERE id = ?", rehashed, user.ID); err == nil {
+				user.Password = rehashed
+			}
+		}
+	}
+
 	return &user, nil
 }
 
+func newSessionID() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate session id: %v", err)
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// CreateSession persists a new session for userID, storing only the hash of
+// the refresh token so a leaked row can't be replayed directly.
+func (d *Database) CreateSession(userID int, refreshTokenHash string, ttl time.Duration) (*Session, error) {
+	id, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &Session{
+		ID:               id,
+		UserID:           userID,
+		RefreshTokenHash: refreshTokenHash,
+		CreatedAt:        now,
+		ExpiresAt:        now.Add(ttl),
+	}
+
+	_, err = d.db.Exec(
+		"INSERT INTO sessions (id, user_id, refresh_token_hash, created_at, expires_at) VALUES (?, ?, ?, ?, ?)",
+		session.ID, session.UserID, session.RefreshTokenHash, session.CreatedAt, session.ExpiresAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %v", err)
+	}
+
+	return session, nil
+}
+
+// GetSessionByRefreshHash looks up a still-unexpired session by the hash of
+// its refresh token.
+func (d *Database) GetSessionByRefreshHash(refreshTokenHash string) (*Session, error) {
+	var s Session
+	err := d.db.QueryRow(
+		"SELECT id, user_id, refresh_token_hash, created_at, expires_at FROM sessions WHERE refresh_token_hash = ? AND expires_at > ?",
+		refreshTokenHash, time.Now(),
+	).Scan(&s.ID, &s.UserID, &s.RefreshTokenHash, &s.CreatedAt, &s.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// DeleteSession removes a session by ID, used on logout and on refresh
+// rotation (the old session is replaced by a new one).
+func (d *Database) DeleteSession(id string) error {
+	_, err := d.db.Exec("DELETE FROM sessions WHERE id = ?", id)
+	return err
+}
+
 func (d *Database) StoreStock(symbol string, price float64) error {
 	d.acquire()
 	defer d.release()
 
+	timestamp := time.Now()
 	var err error
 	for i := 0; i < 3; i++ {
 		_, err = d.db.Exec(
 			"INSERT INTO stocks (symbol, price, timestamp) VALUES (?, ?, ?)",
-			symbol, price, time.Now(),
+			symbol, price, timestamp,
 		)
 		if err == nil {
+			d.notifyWrite(symbol, price, timestamp)
 			return nil
 		}
 		time.Sleep(time.Duration(i+1) * 100 * time.Millisecond)
@@ -220,6 +440,7 @@ func (d *Database) StoreStockWithTimestamp(symbol string, price float64, timesta
 			symbol, price, timestamp,
 		)
 		if err == nil {
+			d.notifyWrite(symbol, price, timestamp)
 			return nil
 		}
 		time.Sleep(time.Duration(i+1) * 100 * time.Millisecond)
@@ -227,16 +448,15 @@ func (d *Database) StoreStockWithTimestamp(symbol string, price float64, timesta
 	return fmt.Errorf("failed to store stock with timestamp after 3 attempts: %v", err)
 }
 
-func (d *Database) GetLatestPrice(symbol string) (float64, error) {
-	ctx, cancel := context.WithTimeout(d.ctx, 5*time.Second)
+func (d *Database) GetLatestPrice(reqCtx context.Context, symbol string) (float64, error) {
+	spanCtx, span := tracer.Start(reqCtx, "db.GetLatestPrice", trace.WithAttributes(attribute.String("symbol", symbol)))
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(spanCtx, 5*time.Second)
 	defer cancel()
 
 	start := time.Now()
-	defer func() {
-		d.metrics.mu.Lock()
-		d.metrics.QueryDuration = time.Since(start).Milliseconds()
-		d.metrics.mu.Unlock()
-	}()
+	defer d.observeQuery("get_latest_price", start)
 
 	var price float64
 	var err error
@@ -250,25 +470,52 @@ func (d *Database) GetLatestPrice(symbol string) (float64, error) {
 		}
 		time.Sleep(time.Duration(i+1) * 100 * time.Millisecond)
 	}
-	return 0, fmt.Errorf("failed to get latest price after 3 attempts: %v", err)
+	err = fmt.Errorf("failed to get latest price after 3 attempts: %v", err)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	return 0, err
 }
 
-func (d *Database) GetHistoricalPrices(symbol string, start, end time.Time) ([]Stock, error) {
-	ctx, cancel := context.WithTimeout(d.ctx, 10*time.Second)
+// SyntheticMode controls whether GetHistoricalPrices pads a sparse real
+// series with GBM-modeled synthetic points, and whether real points are
+// returned alongside them.
+type SyntheticMode string
+
+const (
+	// SyntheticInclude mixes real and synthetic points (the historical
+	// default behavior), with each point tagged via Stock.Synthetic.
+	SyntheticInclude SyntheticMode = "include"
+	// SyntheticExclude returns only rows actually stored in the database.
+	SyntheticExclude SyntheticMode = "exclude"
+	// SyntheticOnly returns only the fabricated padding points, useful for
+	// inspecting what the model would generate.
+	SyntheticOnly SyntheticMode = "only"
+)
+
+// minHistoricalPoints is the series length GetHistoricalPrices pads up to
+// with synthetic points when the real data is sparser than this.
+const minHistoricalPoints = 30
+
+func (d *Database) GetHistoricalPrices(reqCtx context.Context, symbol string, start, end time.Time, mode SyntheticMode) ([]Stock, error) {
+	spanCtx, span := tracer.Start(reqCtx, "db.GetHistoricalPrices", trace.WithAttributes(
+		attribute.String("symbol", symbol),
+		attribute.String("synthetic_mode", string(mode)),
+	))
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(spanCtx, 10*time.Second)
 	defer cancel()
 
 	startTime := time.Now()
-	defer func() {
-		d.metrics.mu.Lock()
-		d.metrics.QueryDuration = time.Since(startTime).Milliseconds()
-		d.metrics.mu.Unlock()
-	}()
+	defer d.observeQuery("get_historical_prices", startTime)
 
 	rows, err := d.db.QueryContext(ctx,
 		"SELECT symbol, price, timestamp FROM stocks WHERE symbol = ? AND timestamp BETWEEN ? AND ? ORDER BY timestamp",
 		symbol, start, end,
 	)
 	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 		return nil, err
 	}
 	defer rows.Close()
@@ -283,42 +530,140 @@ func (d *Database) GetHistoricalPrices(symbol string, start, end time.Time) ([]S
 	}
 
 	if len(stocks) == 0 {
+		span.SetStatus(codes.Error, sql.ErrNoRows.Error())
 		return nil, sql.ErrNoRows
 	}
 
-	if len(stocks) < 30 {
+	if mode == SyntheticExclude {
+		return stocks, rows.Err()
+	}
+
+	var synthetic []Stock
+	if len(stocks) < minHistoricalPoints {
 		var latestPrice float64
 		err := d.db.QueryRowContext(ctx,
 			"SELECT price FROM stocks WHERE symbol = ? ORDER BY timestamp DESC LIMIT 1",
 			symbol,
 		).Scan(&latestPrice)
-		if err != nil {
-			return stocks, rows.Err()
+		if err == nil {
+			synthetic = generateGBMSeries(symbol, latestPrice, stocks, start, end, minHistoricalPoints-len(stocks))
 		}
+	}
 
-		syntheticStocks := generateSyntheticData(symbol, latestPrice, start, end, 30-len(stocks))
-		stocks = append(stocks, syntheticStocks...)
+	if mode == SyntheticOnly {
+		return synthetic, rows.Err()
 	}
 
+	stocks = append(stocks, synthetic...)
 	return stocks, rows.Err()
 }
 
-func generateSyntheticData(symbol string, basePrice float64, start, end time.Time, count int) []Stock {
-	var stocks []Stock
+// gbmParams are the drift (mu) and volatility (sigma) used by geometric
+// Brownian motion, both expressed per year.
+type gbmParams struct {
+	mu    float64
+	sigma float64
+}
+
+// defaultGBMParams are used when fewer than two real points are available
+// to estimate mu/sigma from, roughly a typical large-cap equity.
+var defaultGBMParams = gbmParams{mu: 0.05, sigma: 0.20}
+
+// estimateGBMParams fits mu/sigma by MLE from the log-returns of real,
+// time-ordered points: mu_hat = mean(r)/dt + 0.5*var(r)/dt, sigma_hat =
+// sqrt(var(r)/dt), where dt is the average step size in years.
+func estimateGBMParams(points []Stock) gbmParams {
+	if len(points) < 2 {
+		return defaultGBMParams
+	}
+
+	totalYears := points[len(points)-1].Timestamp.Sub(points[0].Timestamp).Hours() / 24 / 365.25
+	steps := len(points) - 1
+	if totalYears <= 0 || steps == 0 {
+		return defaultGBMParams
+	}
+	dt := totalYears / float64(steps)
+
+	returns := make([]float64, 0, steps)
+	for i := 1; i < len(points); i++ {
+		prev, cur := points[i-1].Price, points[i].Price
+		if prev <= 0 || cur <= 0 {
+			continue
+		}
+		returns = append(returns, math.Log(cur/prev))
+	}
+	if len(returns) < 2 {
+		return defaultGBMParams
+	}
+
+	var mean float64
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+
+	var variance float64
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	variance /= float64(len(returns))
+
+	sigma := math.Sqrt(variance / dt)
+	mu := mean/dt + 0.5*variance/dt
+
+	if sigma <= 0 || math.IsNaN(sigma) || math.IsNaN(mu) {
+		return defaultGBMParams
+	}
+	return gbmParams{mu: mu, sigma: sigma}
+}
+
+// seedFromKey deterministically derives an RNG seed from symbol/start/end
+// so repeated requests for the same range return identical synthetic
+// points instead of a fresh random series defeating the cache every time.
+func seedFromKey(symbol string, start, end time.Time) int64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s|%d|%d", symbol, start.UnixNano(), end.UnixNano())
+	return int64(h.Sum64())
+}
+
+// generateGBMSeries fabricates count points continuing from the last real
+// price via geometric Brownian motion:
+//
+//	S_{t+dt} = S_t * exp((mu - 0.5*sigma^2)*dt + sigma*sqrt(dt)*Z), Z ~ N(0,1)
+//
+// mu/sigma are fit from real's observed log-returns (falling back to
+// defaultGBMParams when fewer than two real points exist), and the RNG is
+// seeded deterministically from symbol|start|end so the series is stable
+// across repeated requests for the same range.
+func generateGBMSeries(symbol string, basePrice float64, real []Stock, start, end time.Time, count int) []Stock {
+	if count <= 0 {
+		return nil
+	}
+
+	params := estimateGBMParams(real)
+	rng := mathrand.New(mathrand.NewSource(seedFromKey(symbol, start, end)))
+
 	duration := end.Sub(start)
 	interval := duration / time.Duration(count)
+	dt := interval.Hours() / 24 / 365.25
+	if dt <= 0 {
+		dt = 1.0 / 365.25
+	}
+
+	stocks := make([]Stock, 0, count)
+	price := basePrice
+	drift := (params.mu - 0.5*params.sigma*params.sigma) * dt
+	vol := params.sigma * math.Sqrt(dt)
 
-	currentPrice := basePrice
 	for i := 0; i < count; i++ {
-		// Generate a random price change between -2% and +2%
-		change := (rand.Float64()*4 - 2) / 100
-		currentPrice = currentPrice * (1 + change)
+		z := rng.NormFloat64()
+		price = price * math.Exp(drift+vol*z)
 
-		timestamp := start.Add(time.Duration(i) * interval)
 		stocks = append(stocks, Stock{
 			Symbol:    symbol,
-			Price:     currentPrice,
-			Timestamp: timestamp,
+			Price:     price,
+			Timestamp: start.Add(time.Duration(i) * interval),
+			Synthetic: true,
 		})
 	}
 
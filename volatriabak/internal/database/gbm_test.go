@@ -0,0 +1,87 @@
+package database
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEstimateGBMParamsFallsBackOnSparseInput(t *testing.T) {
+	cases := [][]Stock{
+		nil,
+		{{Symbol: "AAPL", Price: 100, Timestamp: time.Unix(0, 0)}},
+	}
+	for _, points := range cases {
+		got := estimateGBMParams(points)
+		if got != defaultGBMParams {
+			t.Errorf("estimateGBMParams(%d points) = %+v, want default %+v", len(points), got, defaultGBMParams)
+		}
+	}
+}
+
+func TestEstimateGBMParamsFromRealSeries(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	points := []Stock{
+		{Symbol: "AAPL", Price: 100, Timestamp: start},
+		{Symbol: "AAPL", Price: 101, Timestamp: start.AddDate(0, 0, 1)},
+		{Symbol: "AAPL", Price: 102.5, Timestamp: start.AddDate(0, 0, 2)},
+		{Symbol: "AAPL", Price: 101.5, Timestamp: start.AddDate(0, 0, 3)},
+	}
+
+	params := estimateGBMParams(points)
+	if params.sigma <= 0 || math.IsNaN(params.sigma) || math.IsNaN(params.mu) {
+		t.Fatalf("estimateGBMParams returned invalid params: %+v", params)
+	}
+}
+
+func TestGenerateGBMSeriesDeterministic(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 10)
+	real := []Stock{
+		{Symbol: "AAPL", Price: 100, Timestamp: start},
+		{Symbol: "AAPL", Price: 103, Timestamp: start.AddDate(0, 0, 1)},
+	}
+
+	first := generateGBMSeries("AAPL", 103, real, start, end, 5)
+	second := generateGBMSeries("AAPL", 103, real, start, end, 5)
+
+	if len(first) != 5 || len(second) != 5 {
+		t.Fatalf("got %d and %d points, want 5 each", len(first), len(second))
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			t.Errorf("point %d differs across calls: %+v vs %+v — seedFromKey should make this deterministic", i, first[i], second[i])
+		}
+		if !first[i].Synthetic {
+			t.Errorf("point %d not marked Synthetic", i)
+		}
+	}
+}
+
+func TestGenerateGBMSeriesVariesByRange(t *testing.T) {
+	real := []Stock{
+		{Symbol: "AAPL", Price: 100, Timestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Symbol: "AAPL", Price: 103, Timestamp: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+	}
+
+	a := generateGBMSeries("AAPL", 103, real, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 11, 0, 0, 0, 0, time.UTC), 5)
+	b := generateGBMSeries("AAPL", 103, real, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC), 5)
+
+	same := true
+	for i := range a {
+		if a[i].Price != b[i].Price {
+			same = false
+			break
+		}
+	}
+	if same {
+		t.Error("series for two different [start, end) ranges produced identical prices; seedFromKey should differ by range")
+	}
+}
+
+func TestGenerateGBMSeriesZeroCount(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if got := generateGBMSeries("AAPL", 100, nil, start, start.AddDate(0, 0, 1), 0); got != nil {
+		t.Errorf("generateGBMSeries with count=0 = %v, want nil", got)
+	}
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// defaultSymbolUniverse seeds the symbols table on first run with the set
+// the fetcher previously hard-coded across its live-quote and historical
+// backfill loops, so removing the hardcoded slices doesn't change default
+// behavior for an operator who hasn't touched /admin/symbols yet.
+var defaultSymbolUniverse = []string{
+	"AAPL", "GOOGL", "MSFT", "AMZN", "TSLA",
+	"META", "NVDA", "AMD", "INTC", "IBM",
+	"ORCL", "CSCO", "ADBE", "CRM", "AVGO",
+	"QCOM", "TXN", "MU", "T", "VZ",
+	"DIS", "NFLX", "PYPL", "SQ", "SHOP",
+	"ZM", "DOCU", "SNOW", "DDOG", "CRWD",
+	"ZS", "OKTA", "TEAM", "MDB", "NET",
+	"ASAN", "TWLO", "RNG", "FSLY",
+}
+
+// Symbol is one row of the tracked symbol universe, replacing the fetcher's
+// former hard-coded slices.
+type Symbol struct {
+	Symbol  string
+	Active  bool
+	AddedAt time.Time
+}
+
+// AddSymbol adds symbol to the tracked universe, or reactivates it if it
+// was previously removed.
+func (d *Database) AddSymbol(symbol string) error {
+	_, err := d.db.Exec(
+		`INSERT INTO symbols (symbol, active, added_at) VALUES (?, 1, ?)
+		 ON CONFLICT(symbol) DO UPDATE SET active = 1`,
+		symbol, time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add symbol %s: %v", symbol, err)
+	}
+	return nil
+}
+
+// RemoveSymbol deactivates symbol rather than deleting its row, so any
+// backfill jobs/watermarks already recorded for it stay intact if it's
+// re-added later.
+func (d *Database) RemoveSymbol(symbol string) error {
+	_, err := d.db.Exec("UPDATE symbols SET active = 0 WHERE symbol = ?", symbol)
+	if err != nil {
+		return fmt.Errorf("failed to remove symbol %s: %v", symbol, err)
+	}
+	return nil
+}
+
+// ListSymbols returns the tracked symbol universe, ordered by symbol.
+// activeOnly excludes symbols previously removed via RemoveSymbol.
+func (d *Database) ListSymbols(activeOnly bool) ([]Symbol, error) {
+	query := "SELECT symbol, active, added_at FROM symbols"
+	if activeOnly {
+		query += " WHERE active = 1"
+	}
+	query += " ORDER BY symbol"
+
+	rows, err := d.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var symbols []Symbol
+	for rows.Next() {
+		var s Symbol
+		var active int
+		if err := rows.Scan(&s.Symbol, &active, &s.AddedAt); err != nil {
+			return nil, err
+		}
+		s.Active = active != 0
+		symbols = append(symbols, s)
+	}
+	return symbols, rows.Err()
+}
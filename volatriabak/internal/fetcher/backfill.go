@@ -0,0 +1,274 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	mathrand "math/rand"
+	"sync"
+	"time"
+
+	"github.com/klea/volatria/volatria/internal/database"
+	"github.com/klea/volatria/volatria/internal/telemetry"
+)
+
+// JobType identifies a kind of historical data a Backfiller can pull. Each
+// has its own high-water mark per symbol, so e.g. daily and adjusted-close
+// backfills for the same symbol resume independently.
+type JobType string
+
+const (
+	JobDaily           JobType = "daily"
+	JobAdjustedClose   JobType = "adjusted_close"
+	JobIntraday1m      JobType = "intraday_1m"
+	JobIntraday5m      JobType = "intraday_5m"
+	JobIntraday15m     JobType = "intraday_15m"
+	JobSplitsDividends JobType = "splits_dividends"
+)
+
+// job is a pluggable backfill strategy: given the Fetcher's provider
+// failover, fetch and persist data for symbol over [from, to).
+type job interface {
+	Run(ctx context.Context, f *Fetcher, symbol string, from, to time.Time) error
+}
+
+// dailyJob fetches daily closes via the Fetcher's normal provider failover
+// and stores each point, same as the fetcher's old hardcoded startup pass.
+type dailyJob struct{}
+
+func (dailyJob) Run(ctx context.Context, f *Fetcher, symbol string, from, to time.Time) error {
+	points, err := f.fetchHistoricalWithFailover(ctx, symbol, from, to)
+	if err != nil {
+		return err
+	}
+	for _, point := range points {
+		writeStart := time.Now()
+		err := f.db.StoreStockWithTimestamp(symbol, point.Close, point.Timestamp)
+		f.observeDBWrite("store_with_timestamp", writeStart)
+		if err != nil {
+			return fmt.Errorf("failed to store %s close for %s: %v", symbol, point.Timestamp.Format("2006-01-02"), err)
+		}
+	}
+	return nil
+}
+
+// adjustedCloseJob reuses the same daily series as dailyJob: none of the
+// wired providers (providers.go) expose splits/dividends-adjusted closes
+// separately from raw closes yet. It's tracked as its own JobType, with its
+// own high-water mark, so swapping in a provider that does differentiate
+// them later doesn't require a schema or API change.
+type adjustedCloseJob struct{ dailyJob }
+
+// unsupportedJob is a registered JobType with no provider behind it; it
+// fails honestly instead of silently no-op'ing or faking data. runJob treats
+// that error like any other failure (retried with backoff until
+// maxBackfillAttempts, then marked failed), so the job queue's
+// backoff/last_error columns surface the gap.
+type unsupportedJob struct{ reason string }
+
+func (u unsupportedJob) Run(ctx context.Context, f *Fetcher, symbol string, from, to time.Time) error {
+	return fmt.Errorf("%s", u.reason)
+}
+
+// Backfiller resumes historical ingestion from each symbol/JobType's
+// high-water mark rather than re-pulling the full range on every run,
+// working off a persistent job queue so a crash mid-run loses at most the
+// in-flight job instead of restarting the whole backfill.
+type Backfiller struct {
+	db       *database.Database
+	fetcher  *Fetcher
+	jobs     map[JobType]job
+	interval time.Duration
+	done     chan struct{}
+	stopOnce sync.Once
+
+	metricsExport *telemetry.Metrics
+}
+
+// SetMetrics wires m so queue depth is also exported as a Prometheus gauge.
+func (b *Backfiller) SetMetrics(m *telemetry.Metrics) {
+	b.metricsExport = m
+}
+
+// NewBackfiller wires a Backfiller against f's provider failover and the
+// jobs table db persists. interval controls how often the queue is polled
+// for due work; it has no effect on Enqueue, which always schedules a job
+// to run immediately.
+func NewBackfiller(db *database.Database, f *Fetcher, interval time.Duration) *Backfiller {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	return &Backfiller{
+		db:      db,
+		fetcher: f,
+		jobs: map[JobType]job{
+			JobDaily:           dailyJob{},
+			JobAdjustedClose:   adjustedCloseJob{},
+			JobIntraday1m:      unsupportedJob{reason: "intraday_1m: no configured provider supports intraday quotes"},
+			JobIntraday5m:      unsupportedJob{reason: "intraday_5m: no configured provider supports intraday quotes"},
+			JobIntraday15m:     unsupportedJob{reason: "intraday_15m: no configured provider supports intraday quotes"},
+			JobSplitsDividends: unsupportedJob{reason: "splits_dividends: no configured provider supports corporate actions"},
+		},
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+}
+
+// Enqueue persists a new backfill job for symbol/jobType covering
+// [from, to), for POST /admin/backfill and seeding below. It satisfies
+// api.BackfillEnqueuer.
+func (b *Backfiller) Enqueue(symbol string, jobType string, from, to time.Time) (string, error) {
+	if _, ok := b.jobs[JobType(jobType)]; !ok {
+		return "", fmt.Errorf("unknown backfill job type %q", jobType)
+	}
+	return b.db.EnqueueBackfillJob(symbol, jobType, from, to)
+}
+
+// Start seeds a daily backfill for every active symbol whose high-water
+// mark is behind now, then polls the job queue for due work until Stop is
+// called. Seeding does not check for a job already pending for that
+// symbol, so each call to Start enqueues a new one. It replaces the old Fetcher.fetchHistoricalData startup
+// pass: the symbol universe and progress now live in the database instead
+// of a hardcoded slice and an in-memory pass that re-ran from scratch on
+// every restart.
+func (b *Backfiller) Start(ctx context.Context) error {
+	if err := b.seedDailyJobs(ctx); err != nil {
+		logger.Error().Err(err).Msg("backfill: failed to seed daily jobs")
+	}
+
+	go b.loop(ctx)
+	return nil
+}
+
+func (b *Backfiller) seedDailyJobs(ctx context.Context) error {
+	symbols, err := b.db.ListSymbols(true)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, s := range symbols {
+		from := now.AddDate(-1, 0, 0)
+		if hwm, ok, err := b.db.GetBackfillWatermark(s.Symbol, string(JobDaily)); err == nil && ok {
+			from = hwm
+		}
+		if !from.Before(now) {
+			continue // already caught up
+		}
+		if _, err := b.Enqueue(s.Symbol, string(JobDaily), from, now); err != nil {
+			logger.Error().Str("symbol", s.Symbol).Err(err).Msg("backfill: failed to seed job")
+		}
+	}
+	return nil
+}
+
+func (b *Backfiller) loop(ctx context.Context) {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			b.runDue(ctx)
+		case <-ctx.Done():
+			return
+		case <-b.done:
+			return
+		}
+	}
+}
+
+// runDue claims and runs a small batch of due jobs per tick, rather than
+// draining the whole queue at once, so a burst of enqueued ranges doesn't
+// starve the Fetcher's own provider budget.
+func (b *Backfiller) runDue(ctx context.Context) {
+	const batchSize = 10
+
+	b.observeQueueDepth()
+
+	due, err := b.db.ClaimDueBackfillJobs(batchSize)
+	if err != nil {
+		logger.Error().Err(err).Msg("backfill: failed to claim due jobs")
+		return
+	}
+	for _, j := range due {
+		b.runJob(ctx, j)
+	}
+}
+
+// observeQueueDepth exports the pending/running job counts as a Prometheus
+// gauge, if SetMetrics was called.
+func (b *Backfiller) observeQueueDepth() {
+	if b.metricsExport == nil {
+		return
+	}
+	for _, status := range []string{"pending", "running"} {
+		n, err := b.db.CountBackfillJobs(status)
+		if err != nil {
+			logger.Error().Err(err).Str("status", status).Msg("backfill: failed to count jobs for queue depth")
+			continue
+		}
+		b.metricsExport.BackfillQueueDepth.WithLabelValues(status).Set(float64(n))
+	}
+}
+
+func (b *Backfiller) runJob(ctx context.Context, j database.BackfillJob) {
+	handler, ok := b.jobs[JobType(j.JobType)]
+	if !ok {
+		_ = b.db.InvalidateBackfillJob(j.ID, fmt.Errorf("unknown job type %q", j.JobType))
+		return
+	}
+
+	// Resume from the recorded high-water mark rather than the job's
+	// original range_start, so a crash mid-run doesn't re-pull data this
+	// job (or an earlier one for the same symbol/job_type) already
+	// persisted.
+	from := j.RangeStart
+	if hwm, ok, err := b.db.GetBackfillWatermark(j.Symbol, j.JobType); err == nil && ok && hwm.After(from) {
+		from = hwm
+	}
+	if !from.Before(j.RangeEnd) {
+		_ = b.db.CompleteBackfillJob(j.ID)
+		return
+	}
+
+	if err := handler.Run(ctx, b.fetcher, j.Symbol, from, j.RangeEnd); err != nil {
+		attempts := j.Attempts + 1
+		backoff := backoffWithJitter(attempts)
+		logger.Warn().Str("symbol", j.Symbol).Str("job_type", j.JobType).Int("attempt", attempts).
+			Err(err).Msg("backfill: job failed, retrying with backoff")
+		if ferr := b.db.FailBackfillJob(j.ID, err, attempts, backoff); ferr != nil {
+			logger.Error().Str("job_id", j.ID).Err(ferr).Msg("backfill: failed to record job failure")
+		}
+		return
+	}
+
+	if err := b.db.SetBackfillWatermark(j.Symbol, j.JobType, j.RangeEnd); err != nil {
+		logger.Error().Str("symbol", j.Symbol).Str("job_type", j.JobType).Err(err).Msg("backfill: failed to advance watermark")
+	}
+	if err := b.db.CompleteBackfillJob(j.ID); err != nil {
+		logger.Error().Str("job_id", j.ID).Err(err).Msg("backfill: failed to mark job complete")
+	}
+}
+
+// backoffWithJitter returns an exponential backoff for the given attempt
+// count, capped at 10 minutes and jittered by up to half the base delay so
+// a burst of jobs failing together (e.g. a provider outage) doesn't all
+// retry in lockstep.
+func backoffWithJitter(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	base := time.Second << uint(attempts)
+	const maxBackoff = 10 * time.Minute
+	if base <= 0 || base > maxBackoff {
+		base = maxBackoff
+	}
+	jitter := time.Duration(mathrand.Int63n(int64(base)/2 + 1))
+	return base + jitter
+}
+
+// Stop ends the poll loop. It's safe to call more than once, or concurrently
+// with ctx cancellation from Start: b.done is only ever closed once.
+func (b *Backfiller) Stop() {
+	b.stopOnce.Do(func() { close(b.done) })
+}
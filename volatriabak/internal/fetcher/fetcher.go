@@ -2,23 +2,27 @@ package fetcher
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"log"
 	"net/http"
+	"os"
 	"sync"
 	"time"
 	"unicode"
 
 	"github.com/klea/volatria/volatria/internal/database"
-	"golang.org/x/time/rate"
+	"github.com/klea/volatria/volatria/internal/telemetry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
-const (
-	apiKey     = "d04bbfpr01qm4vp6enf0d04bbfpr01qm4vp6enfg"
-	apiBaseURL = "https://www.alphavantage.co/query"
-)
+// tracer emits a span per fetchStockPriceWithContext/fetchHistoricalWithFailover
+// call, with the provider that served it (or failed) and the retry count as
+// attributes, so a trace correlates with the structured log lines logger
+// emits for the same call.
+var tracer = otel.Tracer("volatria/fetcher")
 
 type FetcherError struct {
 	Symbol string
@@ -33,19 +37,78 @@ type FetcherMetrics struct {
 	TotalRequests     int64
 	FailedRequests    int64
 	SuccessfulFetches int64
-	mu                sync.Mutex
+
+	// CacheHits/CacheMisses/CacheCoalesced count GetQuote's interaction with
+	// the in-process quote cache (see quotecache.go): a hit served a fresh
+	// or stale-while-revalidate entry, a miss triggered an upstream fetch,
+	// and a coalesced miss was one of several concurrent callers for the
+	// same symbol that shared a single upstream call via singleflight.
+	CacheHits      int64
+	CacheMisses    int64
+	CacheCoalesced int64
+
+	mu sync.Mutex
+}
+
+func (m *FetcherMetrics) recordCacheHit() {
+	m.mu.Lock()
+	m.CacheHits++
+	m.mu.Unlock()
+}
+
+func (m *FetcherMetrics) recordCacheMiss() {
+	m.mu.Lock()
+	m.CacheMisses++
+	m.mu.Unlock()
+}
+
+func (m *FetcherMetrics) recordCacheCoalesced() {
+	m.mu.Lock()
+	m.CacheCoalesced++
+	m.mu.Unlock()
 }
 
 type Config struct {
-	APIKey            string
-	BaseURL           string
+	// Providers is the ordered list of data sources fetchStockPriceWithContext
+	// and fetchHistoricalWithFailover try in turn, falling over to the next
+	// one on a retriable *ProviderError. See ProviderConfig.
+	Providers []ProviderConfig
+
 	FetchInterval     time.Duration
 	RequestTimeout    time.Duration
 	MaxConcurrent     int
-	RateLimitPerSec   int
 	RetryCount        int
 	RetryDelay        time.Duration
 	HistoricalTimeout time.Duration
+
+	// QuoteCacheTTL is how long GetQuote treats a cached price as fresh
+	// before falling back to stale-while-revalidate. Zero uses the
+	// quoteCache default (30s).
+	QuoteCacheTTL time.Duration
+}
+
+// defaultConfig mirrors the fetcher's historical single-provider behavior:
+// Alpha Vantage only, keyed from the environment rather than a literal in
+// source.
+func defaultConfig() *Config {
+	return &Config{
+		Providers: []ProviderConfig{
+			{
+				Name:             "alphavantage",
+				APIKey:           os.Getenv("VOLATRIA_ALPHAVANTAGE_API_KEY"),
+				RateLimitPerSec:  5,
+				BreakerThreshold: 5,
+				BreakerCooldown:  30 * time.Second,
+			},
+		},
+		FetchInterval:     1 * time.Minute,
+		RequestTimeout:    10 * time.Second,
+		MaxConcurrent:     5,
+		RetryCount:        3,
+		RetryDelay:        100 * time.Millisecond,
+		HistoricalTimeout: 5 * time.Minute,
+		QuoteCacheTTL:     30 * time.Second,
+	}
 }
 
 type Fetcher struct {
@@ -54,63 +117,120 @@ type Fetcher struct {
 	done       chan struct{}
 	lastPrices map[string]float64
 	client     *http.Client
-	limiter    *rate.Limiter
+	providers  []*managedProvider
 	metrics    *FetcherMetrics
+	quotes     *quoteCache
 	config     *Config
 	isRunning  bool
 	mu         sync.RWMutex
+	stopOnce   sync.Once
+	loopWG     sync.WaitGroup
+
+	metricsExport *telemetry.Metrics
+}
+
+// SetMetrics wires m so provider request latency, retries, rate-limit
+// waits, and DB write latency are also exported as Prometheus collectors,
+// alongside the existing FetcherMetrics snapshot. Only one Metrics is
+// supported; calling this again replaces the previous one.
+func (f *Fetcher) SetMetrics(m *telemetry.Metrics) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.metricsExport = m
 }
 
-type AlphaVantageResponse struct {
-	GlobalQuote struct {
-		Symbol string `json:"01. symbol"`
-		Price  string `json:"05. price"`
-	} `json:"Global Quote"`
+func (f *Fetcher) observeProviderRequest(provider string, start time.Time) {
+	f.mu.RLock()
+	export := f.metricsExport
+	f.mu.RUnlock()
+	if export != nil {
+		export.ProviderRequestDuration.WithLabelValues(provider).Observe(time.Since(start).Seconds())
+	}
 }
 
-type HistoricalDataResponse struct {
-	TimeSeriesDaily map[string]struct {
-		Close string `json:"4. close"`
-	} `json:"Time Series (Daily)"`
+func (f *Fetcher) observeRateLimitWait(provider string, start time.Time) {
+	f.mu.RLock()
+	export := f.metricsExport
+	f.mu.RUnlock()
+	if export != nil {
+		export.ProviderRateLimitWait.WithLabelValues(provider).Observe(time.Since(start).Seconds())
+	}
+}
+
+func (f *Fetcher) observeRetry(provider string) {
+	f.mu.RLock()
+	export := f.metricsExport
+	f.mu.RUnlock()
+	if export != nil {
+		export.ProviderRetries.WithLabelValues(provider).Inc()
+	}
+}
+
+func (f *Fetcher) observeDBWrite(op string, start time.Time) {
+	f.mu.RLock()
+	export := f.metricsExport
+	f.mu.RUnlock()
+	if export != nil {
+		export.DBWriteDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+	}
 }
 
 func New(db *database.Database, config *Config) *Fetcher {
 	if config == nil {
-		config = &Config{
-			APIKey:            apiKey,
-			BaseURL:           apiBaseURL,
-			FetchInterval:     1 * time.Minute,
-			RequestTimeout:    10 * time.Second,
-			MaxConcurrent:     5,
-			RateLimitPerSec:   5,
-			RetryCount:        3,
-			RetryDelay:        100 * time.Millisecond,
-			HistoricalTimeout: 5 * time.Minute,
-		}
+		config = defaultConfig()
+	}
+
+	client := &http.Client{
+		Timeout: config.RequestTimeout,
+		Transport: &http.Transport{
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+			DisableCompression:  true,
+			MaxConnsPerHost:     10,
+			MaxIdleConnsPerHost: 10,
+		},
 	}
 
+	providers, err := buildProviders(config.Providers, client)
+	if err != nil {
+		// A misconfigured provider name is a startup-time mistake, not a
+		// runtime condition to fail over on, so surface it loudly rather
+		// than silently running with zero providers.
+		logger.Fatal().Err(err).Msg("fetcher: misconfigured provider")
+	}
+
+	metrics := &FetcherMetrics{}
+
 	return &Fetcher{
 		db:         db,
 		ticker:     time.NewTicker(config.FetchInterval),
 		done:       make(chan struct{}),
 		lastPrices: make(map[string]float64),
-		client: &http.Client{
-			Timeout: config.RequestTimeout,
-			Transport: &http.Transport{
-				MaxIdleConns:        100,
-				IdleConnTimeout:     90 * time.Second,
-				DisableCompression:  true,
-				MaxConnsPerHost:     10,
-				MaxIdleConnsPerHost: 10,
-			},
-		},
-		limiter: rate.NewLimiter(rate.Every(time.Second/time.Duration(config.RateLimitPerSec)), 1),
-		metrics: &FetcherMetrics{},
-		config:  config,
+		client:     client,
+		providers:  providers,
+		metrics:    metrics,
+		quotes:     newQuoteCache(config.QuoteCacheTTL, metrics),
+		config:     config,
 	}
 }
 
-func (f *Fetcher) Start() error {
+// GetQuote returns symbol's latest price from the in-process quote cache
+// when it's fresh; once an entry is older than the cache TTL it is still
+// served immediately while a background call refreshes it, rather than
+// blocking on the provider. A genuine miss coalesces concurrent callers via singleflight
+// and falls through to fetchStockPriceWithContext.
+func (f *Fetcher) GetQuote(ctx context.Context, symbol string) (float64, error) {
+	return f.quotes.Get(symbol, func() (float64, error) {
+		return f.fetchStockPriceWithContext(ctx, symbol)
+	})
+}
+
+// Start begins the poll loop, fetching once immediately and then every
+// f.config's interval until ctx is cancelled or Stop is called, whichever
+// comes first — both stop the same goroutine, so a caller that propagates
+// its own shutdown context doesn't also need to call Stop for that goroutine
+// to exit promptly.
+func (f *Fetcher) Start(ctx context.Context) error {
 	f.mu.Lock()
 	if f.isRunning {
 		f.mu.Unlock()
@@ -119,14 +239,18 @@ func (f *Fetcher) Start() error {
 	f.isRunning = true
 	f.mu.Unlock()
 
-	f.fetchHistoricalData()
-	f.fetchPrices()
+	f.fetchPrices(ctx)
 
+	f.loopWG.Add(1)
 	go func() {
+		defer f.loopWG.Done()
 		for {
 			select {
 			case <-f.ticker.C:
-				f.fetchPrices()
+				f.fetchPrices(ctx)
+			case <-ctx.Done():
+				f.ticker.Stop()
+				return
 			case <-f.done:
 				f.ticker.Stop()
 				return
@@ -137,6 +261,12 @@ func (f *Fetcher) Start() error {
 	return nil
 }
 
+// Stop ends the poll loop and waits for any tick currently in flight (and
+// the per-symbol GetQuote/StoreStock goroutines it spawned) to finish
+// before returning, so a caller that closes the database right after Stop
+// doesn't race an in-flight write. It's safe to call more than once,
+// including after a failed or never-started Start, or concurrently with
+// ctx cancellation from Start: f.done is only ever closed once.
 func (f *Fetcher) Stop() error {
 	f.mu.Lock()
 	if !f.isRunning {
@@ -146,7 +276,8 @@ func (f *Fetcher) Stop() error {
 	f.isRunning = false
 	f.mu.Unlock()
 
-	close(f.done)
+	f.stopOnce.Do(func() { close(f.done) })
+	f.loopWG.Wait()
 	return nil
 }
 
@@ -161,7 +292,9 @@ func (f *Fetcher) HealthCheck() error {
 		return fmt.Errorf("fetcher not running")
 	}
 
-	// Test a simple stock fetch
+	// Bypass the quote cache: a health check exists to test the provider
+	// path itself, and a stale-while-revalidate hit would let it report
+	// healthy off a cached price even if every provider were down.
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -181,243 +314,200 @@ func isValidStockSymbol(symbol string) bool {
 	return true
 }
 
+// fetchStockPriceWithContext tries each configured provider in order,
+// skipping one whose circuit breaker is open or whose request budget is
+// exhausted, and failing over to the next on a retriable *ProviderError
+// (quota/5xx/timeout). A non-retriable error (e.g. an unknown symbol) is
+// returned immediately, since every provider would reject it the same way.
 func (f *Fetcher) fetchStockPriceWithContext(ctx context.Context, symbol string) (float64, error) {
+	ctx, span := tracer.Start(ctx, "fetcher.fetchStockPriceWithContext",
+		trace.WithAttributes(attribute.String("symbol", symbol)))
+	defer span.End()
+
+	traceID := span.SpanContext().TraceID().String()
+
 	if !isValidStockSymbol(symbol) {
-		return 0, fmt.Errorf("invalid stock symbol: %s", symbol)
+		err := fmt.Errorf("invalid stock symbol: %s", symbol)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
 	}
 
-	if err := f.limiter.Wait(ctx); err != nil {
-		return 0, fmt.Errorf("rate limit exceeded: %v", err)
+	if len(f.providers) == 0 {
+		err := fmt.Errorf("no data providers configured")
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
 	}
 
-	url := fmt.Sprintf("%s?function=GLOBAL_QUOTE&symbol=%s&apikey=%s", apiBaseURL, symbol, apiKey)
-
 	var lastErr error
-	for i := 0; i < 3; i++ {
-		resp, err := f.client.Get(url)
-		if err != nil {
-			lastErr = fmt.Errorf("HTTP request failed for %s: %v", symbol, err)
-			time.Sleep(time.Duration(i+1) * 100 * time.Millisecond)
+	attempt := 0
+	for _, mp := range f.providers {
+		if !mp.breaker.allow() || !mp.budget.allow() {
 			continue
 		}
-
-		body, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			lastErr = fmt.Errorf("failed to read response body for %s: %v", symbol, err)
-			time.Sleep(time.Duration(i+1) * 100 * time.Millisecond)
-			continue
+		attempt++
+		provider := mp.provider.Name()
+
+		waitStart := time.Now()
+		if err := mp.limiter.Wait(ctx); err != nil {
+			err = fmt.Errorf("rate limit exceeded: %v", err)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return 0, err
 		}
-
-		var result AlphaVantageResponse
-		if err := json.Unmarshal(body, &result); err != nil {
-			lastErr = fmt.Errorf("failed to decode JSON for %s: %v", symbol, err)
-			time.Sleep(time.Duration(i+1) * 100 * time.Millisecond)
-			continue
+		f.observeRateLimitWait(provider, waitStart)
+
+		reqStart := time.Now()
+		quote, err := mp.provider.FetchQuote(ctx, symbol)
+		f.observeProviderRequest(provider, reqStart)
+
+		if err == nil {
+			mp.breaker.recordSuccess()
+			span.SetAttributes(attribute.String("provider", provider), attribute.Int("attempt", attempt))
+			logger.Debug().Str("symbol", symbol).Str("provider", provider).Int("attempt", attempt).
+				Str("trace_id", traceID).Msg("fetched quote")
+			return quote.Price, nil
 		}
 
-		if result.GlobalQuote.Price == "" {
-			lastErr = fmt.Errorf("no price data available for %s", symbol)
-			time.Sleep(time.Duration(i+1) * 100 * time.Millisecond)
-			continue
-		}
+		lastErr = err
+		logger.Warn().Str("symbol", symbol).Str("provider", provider).Int("attempt", attempt).
+			Str("trace_id", traceID).Err(err).Msg("provider request failed")
 
-		price, err := parsePrice(result.GlobalQuote.Price)
-		if err != nil {
-			lastErr = fmt.Errorf("failed to parse price for %s: %v", symbol, err)
-			time.Sleep(time.Duration(i+1) * 100 * time.Millisecond)
-			continue
+		var perr *ProviderError
+		if errors.As(err, &perr) && !perr.Retriable {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return 0, err
 		}
-
-		return price, nil
+		mp.breaker.recordFailure()
+		f.observeRetry(provider)
 	}
-	return 0, lastErr
-}
 
-func (f *Fetcher) recordRequest(success bool) {
-	f.metrics.mu.Lock()
-	defer f.metrics.mu.Unlock()
-	f.metrics.TotalRequests++
-	if success {
-		f.metrics.SuccessfulFetches++
-	} else {
-		f.metrics.FailedRequests++
+	if lastErr == nil {
+		lastErr = fmt.Errorf("every provider's circuit breaker is open or budget is exhausted")
 	}
+	err := fmt.Errorf("all providers failed for %s: %w", symbol, lastErr)
+	span.SetAttributes(attribute.Int("attempt", attempt))
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	return 0, err
 }
 
-func (f *Fetcher) fetchHistoricalData() {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
-
-	symbols := []string{
-		"AAPL", "GOOGL", "MSFT", "AMZN", "TSLA",
-		"META", "NVDA", "AMD", "INTC", "IBM",
-		"ORCL", "CSCO", "ADBE", "CRM", "AVGO",
-		"QCOM", "TXN", "MU", "T", "VZ",
-		"DIS", "NFLX", "PYPL", "SQ", "SHOP",
-		"ZM", "DOCU", "SNOW", "DDOG", "CRWD",
-		"ZS", "OKTA", "TEAM", "MDB", "NET",
-		"ASAN", "TWLO", "RNG", "FSLY",
-	}
+// fetchHistoricalWithFailover is fetchStockPriceWithContext's historical-data counterpart,
+// using the same breaker/budget/failover rules.
+func (f *Fetcher) fetchHistoricalWithFailover(ctx context.Context, symbol string, from, to time.Time) ([]HistoricalPoint, error) {
+	ctx, span := tracer.Start(ctx, "fetcher.fetchHistoricalWithFailover",
+		trace.WithAttributes(attribute.String("symbol", symbol)))
+	defer span.End()
 
-	var wg sync.WaitGroup
-	sem := make(chan struct{}, 5) // Limit concurrent requests
-
-	for _, symbol := range symbols {
-		select {
-		case <-ctx.Done():
-			return
-		case sem <- struct{}{}:
-			wg.Add(1)
-			go func(s string) {
-				defer wg.Done()
-				defer func() { <-sem }()
-
-				if err := f.limiter.Wait(ctx); err != nil {
-					f.recordRequest(false)
-					log.Printf("Rate limit exceeded for %s: %v", s, err)
-					return
-				}
-
-				url := fmt.Sprintf("%s?function=TIME_SERIES_DAILY&symbol=%s&apikey=%s", apiBaseURL, s, apiKey)
-				var lastErr error
-
-				for i := 0; i < 3; i++ {
-					req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-					if err != nil {
-						lastErr = err
-						continue
-					}
-
-					resp, err := f.client.Do(req)
-					if err != nil {
-						lastErr = err
-						time.Sleep(time.Duration(i+1) * 100 * time.Millisecond)
-						continue
-					}
-
-					body, err := io.ReadAll(resp.Body)
-					resp.Body.Close()
-					if err != nil {
-						lastErr = err
-						time.Sleep(time.Duration(i+1) * 100 * time.Millisecond)
-						continue
-					}
-
-					var result HistoricalDataResponse
-					if err := json.Unmarshal(body, &result); err != nil {
-						lastErr = err
-						time.Sleep(time.Duration(i+1) * 100 * time.Millisecond)
-						continue
-					}
-
-					for date, data := range result.TimeSeriesDaily {
-						price, err := parsePrice(data.Close)
-						if err != nil {
-							log.Printf("Failed to parse historical price for %s on %s: %v", s, date, err)
-							continue
-						}
-						timestamp, err := time.Parse("2006-01-02", date)
-						if err != nil {
-							log.Printf("Failed to parse timestamp for %s on %s: %v", s, date, err)
-							continue
-						}
-						if err := f.db.StoreStockWithTimestamp(s, price, timestamp); err != nil {
-							log.Printf("Error storing historical price for %s on %s: %v", s, date, err)
-						}
-					}
-
-					f.recordRequest(true)
-					return
-				}
+	traceID := span.SpanContext().TraceID().String()
 
-				f.recordRequest(false)
-				log.Printf("Failed to fetch historical data for %s after 3 attempts: %v", s, lastErr)
-			}(symbol)
-		}
+	if len(f.providers) == 0 {
+		err := fmt.Errorf("no data providers configured")
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
 	}
 
-	wg.Wait()
-}
-
-func (f *Fetcher) fetchStockPrice(symbol string) (float64, error) {
-	if err := f.limiter.Wait(context.Background()); err != nil {
-		return 0, fmt.Errorf("rate limit exceeded: %v", err)
-	}
-
-	url := fmt.Sprintf("%s?function=GLOBAL_QUOTE&symbol=%s&apikey=%s", apiBaseURL, symbol, apiKey)
-
 	var lastErr error
-	for i := 0; i < 3; i++ {
-		resp, err := f.client.Get(url)
-		if err != nil {
-			lastErr = fmt.Errorf("HTTP request failed for %s: %v", symbol, err)
-			time.Sleep(time.Duration(i+1) * 100 * time.Millisecond)
+	attempt := 0
+	for _, mp := range f.providers {
+		if !mp.breaker.allow() || !mp.budget.allow() {
 			continue
 		}
-
-		body, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			lastErr = fmt.Errorf("failed to read response body for %s: %v", symbol, err)
-			time.Sleep(time.Duration(i+1) * 100 * time.Millisecond)
-			continue
+		attempt++
+		provider := mp.provider.Name()
+
+		waitStart := time.Now()
+		if err := mp.limiter.Wait(ctx); err != nil {
+			err = fmt.Errorf("rate limit exceeded: %v", err)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
 		}
-
-		var result AlphaVantageResponse
-		if err := json.Unmarshal(body, &result); err != nil {
-			lastErr = fmt.Errorf("failed to decode JSON for %s: %v", symbol, err)
-			time.Sleep(time.Duration(i+1) * 100 * time.Millisecond)
-			continue
+		f.observeRateLimitWait(provider, waitStart)
+
+		reqStart := time.Now()
+		points, err := mp.provider.FetchHistorical(ctx, symbol, from, to)
+		f.observeProviderRequest(provider, reqStart)
+
+		if err == nil {
+			mp.breaker.recordSuccess()
+			span.SetAttributes(attribute.String("provider", provider), attribute.Int("attempt", attempt))
+			logger.Debug().Str("symbol", symbol).Str("provider", provider).Int("attempt", attempt).
+				Str("trace_id", traceID).Msg("fetched historical series")
+			return points, nil
 		}
 
-		if result.GlobalQuote.Price == "" {
-			lastErr = fmt.Errorf("no price data available for %s", symbol)
-			time.Sleep(time.Duration(i+1) * 100 * time.Millisecond)
-			continue
-		}
+		lastErr = err
+		logger.Warn().Str("symbol", symbol).Str("provider", provider).Int("attempt", attempt).
+			Str("trace_id", traceID).Err(err).Msg("provider historical request failed")
 
-		price, err := parsePrice(result.GlobalQuote.Price)
-		if err != nil {
-			lastErr = fmt.Errorf("failed to parse price for %s: %v", symbol, err)
-			time.Sleep(time.Duration(i+1) * 100 * time.Millisecond)
-			continue
+		var perr *ProviderError
+		if errors.As(err, &perr) && !perr.Retriable {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
 		}
+		mp.breaker.recordFailure()
+		f.observeRetry(provider)
+	}
 
-		return price, nil
+	if lastErr == nil {
+		lastErr = fmt.Errorf("every provider's circuit breaker is open or budget is exhausted")
 	}
-	return 0, lastErr
+	err := fmt.Errorf("all providers failed for %s: %w", symbol, lastErr)
+	span.SetAttributes(attribute.Int("attempt", attempt))
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	return nil, err
 }
 
-func parsePrice(priceStr string) (float64, error) {
-	var price float64
-	if _, err := fmt.Sscanf(priceStr, "%f", &price); err != nil {
-		return 0, fmt.Errorf("failed to parse price: %v", err)
+func (f *Fetcher) recordRequest(success bool) {
+	f.metrics.mu.Lock()
+	defer f.metrics.mu.Unlock()
+	f.metrics.TotalRequests++
+	if success {
+		f.metrics.SuccessfulFetches++
+	} else {
+		f.metrics.FailedRequests++
 	}
-	return price, nil
 }
 
-func (f *Fetcher) fetchPrices() {
-	symbols := []string{
-		"AAPL", "GOOGL", "MSFT", "AMZN", "TSLA",
-		"META", "NVDA", "AMD", "INTC", "IBM",
+// fetchPrices polls the current active symbol universe (see database's
+// symbols table) for a live quote each tick, passing ctx through to every
+// per-symbol GetQuote call so a shutdown cancels in-flight provider
+// requests instead of waiting them out. Historical ingestion no longer
+// happens here; it's handled by the Backfiller, which resumes from a
+// persisted high-water mark instead of re-pulling a year of data on every
+// restart.
+func (f *Fetcher) fetchPrices(ctx context.Context) {
+	symbols, err := f.db.ListSymbols(true)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to list symbols")
+		return
 	}
 
 	var wg sync.WaitGroup
-	for _, symbol := range symbols {
+	for _, s := range symbols {
 		wg.Add(1)
 		go func(s string) {
 			defer wg.Done()
-			price, err := f.fetchStockPrice(s)
+			price, err := f.GetQuote(ctx, s)
 			if err != nil {
-				log.Printf("Error fetching price for %s: %v", s, err)
+				f.recordRequest(false)
+				logger.Error().Str("symbol", s).Err(err).Msg("failed to fetch price")
 				return
 			}
+			f.recordRequest(true)
 
+			writeStart := time.Now()
 			if err := f.db.StoreStock(s, price); err != nil {
-				log.Printf("Error storing price for %s: %v", s, err)
+				logger.Error().Str("symbol", s).Err(err).Msg("failed to store price")
 			}
-		}(symbol)
+			f.observeDBWrite("store", writeStart)
+		}(s.Symbol)
 	}
 	wg.Wait()
 }
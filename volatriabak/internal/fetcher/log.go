@@ -0,0 +1,13 @@
+package fetcher
+
+import (
+	"os"
+
+	"github.com/rs/zerolog"
+)
+
+// logger is the fetcher package's structured logger, replacing the plain
+// log.Printf calls the provider-failover and backfill code used to make.
+// Call sites attach symbol/provider/attempt/trace_id fields so a log line
+// can be correlated with the span tracer emits for the same call.
+var logger = zerolog.New(os.Stderr).With().Timestamp().Str("component", "fetcher").Logger()
@@ -0,0 +1,248 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Quote is a provider's normalized response to a point-in-time price
+// request, after mapping away whatever field names/units that provider
+// happens to use (e.g. Finnhub's "c", IEX's "latestPrice").
+type Quote struct {
+	Symbol string
+	Price  float64
+}
+
+// HistoricalPoint is one normalized daily close from a provider's
+// historical series.
+type HistoricalPoint struct {
+	Timestamp time.Time
+	Close     float64
+}
+
+// Provider is a single upstream market-data source. Implementations map
+// their own response shape into Quote/HistoricalPoint and should return a
+// *ProviderError with Retriable set for conditions the Fetcher's failover
+// should try the next provider for (quota exceeded, 5xx, timeout) as
+// opposed to a hard failure (e.g. unknown symbol) that every provider
+// would reject identically.
+type Provider interface {
+	Name() string
+	FetchQuote(ctx context.Context, symbol string) (Quote, error)
+	FetchHistorical(ctx context.Context, symbol string, from, to time.Time) ([]HistoricalPoint, error)
+}
+
+// ProviderError wraps a provider-specific failure with whether the Fetcher
+// should fail over to the next configured provider.
+type ProviderError struct {
+	Provider  string
+	Err       error
+	Retriable bool
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Provider, e.Err)
+}
+
+func (e *ProviderError) Unwrap() error { return e.Err }
+
+// parsePrice parses a decimal price string, the shape Alpha Vantage (and a
+// couple of others) return instead of a JSON number.
+func parsePrice(priceStr string) (float64, error) {
+	var price float64
+	if _, err := fmt.Sscanf(priceStr, "%f", &price); err != nil {
+		return 0, fmt.Errorf("failed to parse price: %v", err)
+	}
+	return price, nil
+}
+
+// breakerState is the circuit-breaker state machine for one provider:
+// closed (healthy) -> open (failing, short-circuited) -> half-open (one
+// trial call allowed after cooldown) -> closed or back to open.
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// providerBreaker is the per-provider analogue of api.CircuitBreaker: it
+// trips after threshold consecutive failures and stays open for cooldown
+// before allowing a single half-open trial call through.
+type providerBreaker struct {
+	mu        sync.Mutex
+	state     breakerState
+	failures  int
+	threshold int
+	cooldown  time.Duration
+	openedAt  time.Time
+}
+
+func newProviderBreaker(threshold int, cooldown time.Duration) *providerBreaker {
+	if threshold <= 0 {
+		threshold = 5
+	}
+	if cooldown <= 0 {
+		cooldown = 30 * time.Second
+	}
+	return &providerBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// allow reports whether a call against this provider should be attempted,
+// transitioning open -> half-open once cooldown has elapsed.
+func (b *providerBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != breakerOpen {
+		return true
+	}
+	if time.Since(b.openedAt) < b.cooldown {
+		return false
+	}
+	b.state = breakerHalfOpen
+	return true
+}
+
+func (b *providerBreaker) recordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.state = breakerClosed
+}
+
+func (b *providerBreaker) recordFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+func (b *providerBreaker) State() breakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// budgetTracker caps a provider to limit calls per window, for free-tier
+// daily/monthly request quotas. A nil *budgetTracker (limit <= 0) never
+// blocks.
+type budgetTracker struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	count   int
+	resetAt time.Time
+}
+
+func newBudgetTracker(limit int, window time.Duration) *budgetTracker {
+	if limit <= 0 {
+		return nil
+	}
+	if window <= 0 {
+		window = 24 * time.Hour
+	}
+	return &budgetTracker{limit: limit, window: window, resetAt: time.Now().Add(window)}
+}
+
+func (b *budgetTracker) allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if time.Now().After(b.resetAt) {
+		b.count = 0
+		b.resetAt = time.Now().Add(b.window)
+	}
+	if b.count >= b.limit {
+		return false
+	}
+	b.count++
+	return true
+}
+
+// ProviderConfig configures one entry in Config.Providers. The same
+// provider Name may appear only once; New builds providers in the order
+// given, and fetchStockPriceWithContext/fetchHistoricalWithFailover try
+// them in that order.
+type ProviderConfig struct {
+	// Name selects the provider implementation: "alphavantage", "iex",
+	// "finnhub", "yahoo", or "polygon".
+	Name string
+	// APIKey is required by every provider except Yahoo Finance's
+	// unofficial endpoint.
+	APIKey string
+	// BaseURL overrides the provider's default endpoint, mainly for tests.
+	BaseURL string
+
+	RateLimitPerSec int
+	// Budget caps requests per BudgetWindow (e.g. a free-tier daily quota);
+	// zero disables the cap.
+	Budget       int
+	BudgetWindow time.Duration
+
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+// managedProvider pairs a Provider with the rate limiting, budget, and
+// circuit-breaker state the Fetcher enforces around it.
+type managedProvider struct {
+	provider Provider
+	breaker  *providerBreaker
+	limiter  *rate.Limiter
+	budget   *budgetTracker
+}
+
+// buildProviders constructs the ordered managedProvider list for cfgs,
+// sharing httpClient across providers the way the old single-provider
+// Fetcher shared one client for every request.
+func buildProviders(cfgs []ProviderConfig, httpClient *http.Client) ([]*managedProvider, error) {
+	managed := make([]*managedProvider, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		provider, err := newProvider(cfg, httpClient)
+		if err != nil {
+			return nil, err
+		}
+
+		rps := cfg.RateLimitPerSec
+		if rps <= 0 {
+			rps = 5
+		}
+
+		managed = append(managed, &managedProvider{
+			provider: provider,
+			breaker:  newProviderBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown),
+			limiter:  rate.NewLimiter(rate.Every(time.Second/time.Duration(rps)), 1),
+			budget:   newBudgetTracker(cfg.Budget, cfg.BudgetWindow),
+		})
+	}
+	return managed, nil
+}
+
+// newProvider is the Name -> Provider factory backing buildProviders.
+func newProvider(cfg ProviderConfig, httpClient *http.Client) (Provider, error) {
+	switch cfg.Name {
+	case "alphavantage":
+		return newAlphaVantageProvider(cfg, httpClient), nil
+	case "iex":
+		return newIEXCloudProvider(cfg, httpClient), nil
+	case "finnhub":
+		return newFinnhubProvider(cfg, httpClient), nil
+	case "yahoo":
+		return newYahooFinanceProvider(cfg, httpClient), nil
+	case "polygon":
+		return newPolygonProvider(cfg, httpClient), nil
+	default:
+		return nil, fmt.Errorf("unknown data provider %q", cfg.Name)
+	}
+}
@@ -0,0 +1,191 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestProviderBreakerOpensAfterThresholdFailures(t *testing.T) {
+	b := newProviderBreaker(3, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		if !b.allow() {
+			t.Fatalf("allow() = false before threshold reached (failure %d)", i)
+		}
+		b.recordFailure()
+	}
+	if b.State() != breakerClosed {
+		t.Fatalf("State() = %v after 2/3 failures, want breakerClosed", b.State())
+	}
+
+	b.recordFailure()
+	if b.State() != breakerOpen {
+		t.Fatalf("State() = %v after reaching threshold, want breakerOpen", b.State())
+	}
+	if b.allow() {
+		t.Fatal("allow() = true immediately after opening, want false during cooldown")
+	}
+}
+
+func TestProviderBreakerHalfOpenTrialSuccessCloses(t *testing.T) {
+	b := newProviderBreaker(1, time.Minute)
+	b.recordFailure() // opens on the first failure, threshold=1
+
+	// Simulate the cooldown having elapsed without sleeping.
+	b.openedAt = time.Now().Add(-2 * time.Minute)
+
+	if !b.allow() {
+		t.Fatal("allow() = false once cooldown has elapsed, want true (half-open trial)")
+	}
+	if b.State() != breakerHalfOpen {
+		t.Fatalf("State() = %v after cooldown elapsed, want breakerHalfOpen", b.State())
+	}
+
+	b.recordSuccess()
+	if b.State() != breakerClosed {
+		t.Fatalf("State() = %v after a successful half-open trial, want breakerClosed", b.State())
+	}
+}
+
+func TestProviderBreakerHalfOpenTrialFailureReopens(t *testing.T) {
+	b := newProviderBreaker(1, time.Minute)
+	b.recordFailure()
+	b.openedAt = time.Now().Add(-2 * time.Minute)
+
+	if !b.allow() {
+		t.Fatal("expected the half-open trial call to be allowed")
+	}
+
+	b.recordFailure()
+	if b.State() != breakerOpen {
+		t.Fatalf("State() = %v after the half-open trial failed, want breakerOpen again", b.State())
+	}
+	if b.allow() {
+		t.Fatal("allow() = true right after reopening, want false during the new cooldown")
+	}
+}
+
+func TestBudgetTrackerEnforcesLimitAndResetsAfterWindow(t *testing.T) {
+	b := newBudgetTracker(2, time.Minute)
+
+	if !b.allow() || !b.allow() {
+		t.Fatal("first two calls within the budget should be allowed")
+	}
+	if b.allow() {
+		t.Fatal("third call should exceed the budget of 2 per window")
+	}
+
+	// Simulate the window having elapsed without sleeping.
+	b.resetAt = time.Now().Add(-time.Second)
+	if !b.allow() {
+		t.Fatal("call after the window resets should be allowed again")
+	}
+}
+
+func TestBudgetTrackerNilIsAlwaysAllowed(t *testing.T) {
+	b := newBudgetTracker(0, time.Minute)
+	if b != nil {
+		t.Fatalf("newBudgetTracker(0, ...) = %v, want nil (no budget configured)", b)
+	}
+	if !b.allow() {
+		t.Fatal("a nil *budgetTracker should always allow")
+	}
+}
+
+// fakeProvider is a Provider whose FetchQuote behavior is scripted per call,
+// for exercising fetchStockPriceWithContext's failover ordering without a
+// real upstream.
+type fakeProvider struct {
+	name  string
+	calls int
+	errs  []error
+	price float64
+}
+
+func (p *fakeProvider) Name() string { return p.name }
+
+func (p *fakeProvider) FetchQuote(ctx context.Context, symbol string) (Quote, error) {
+	i := p.calls
+	p.calls++
+	if i < len(p.errs) && p.errs[i] != nil {
+		return Quote{}, p.errs[i]
+	}
+	return Quote{Symbol: symbol, Price: p.price}, nil
+}
+
+func (p *fakeProvider) FetchHistorical(ctx context.Context, symbol string, from, to time.Time) ([]HistoricalPoint, error) {
+	return nil, errors.New("not implemented")
+}
+
+func newTestManagedProvider(p Provider) *managedProvider {
+	return &managedProvider{
+		provider: p,
+		breaker:  newProviderBreaker(5, 30*time.Second),
+		limiter:  rate.NewLimiter(rate.Inf, 1),
+		budget:   nil,
+	}
+}
+
+func TestFetchStockPriceFailsOverToNextProviderOnRetriableError(t *testing.T) {
+	first := &fakeProvider{name: "flaky", errs: []error{&ProviderError{Provider: "flaky", Err: errors.New("rate limited"), Retriable: true}}}
+	second := &fakeProvider{name: "stable", price: 150.0}
+
+	f := &Fetcher{providers: []*managedProvider{newTestManagedProvider(first), newTestManagedProvider(second)}}
+
+	price, err := f.fetchStockPriceWithContext(context.Background(), "AAPL")
+	if err != nil {
+		t.Fatalf("fetchStockPriceWithContext returned error: %v", err)
+	}
+	if price != 150.0 {
+		t.Errorf("price = %v, want 150.0 from the second provider", price)
+	}
+	if first.calls != 1 {
+		t.Errorf("first provider was called %d times, want exactly 1 before failing over", first.calls)
+	}
+	if second.calls != 1 {
+		t.Errorf("second provider was called %d times, want exactly 1", second.calls)
+	}
+}
+
+func TestFetchStockPriceReturnsImmediatelyOnNonRetriableError(t *testing.T) {
+	first := &fakeProvider{name: "strict", errs: []error{&ProviderError{Provider: "strict", Err: errors.New("unknown symbol"), Retriable: false}}}
+	second := &fakeProvider{name: "backup", price: 150.0}
+
+	f := &Fetcher{providers: []*managedProvider{newTestManagedProvider(first), newTestManagedProvider(second)}}
+
+	_, err := f.fetchStockPriceWithContext(context.Background(), "AAPL")
+	if err == nil {
+		t.Fatal("expected a non-retriable provider error to surface, got nil")
+	}
+	if second.calls != 0 {
+		t.Errorf("second provider was called %d times, want 0 since the first error was non-retriable", second.calls)
+	}
+}
+
+func TestFetchStockPriceSkipsProviderWithOpenBreaker(t *testing.T) {
+	down := &fakeProvider{name: "down", price: 100.0}
+	downMP := newTestManagedProvider(down)
+	downMP.breaker.recordFailure()
+	downMP.breaker.threshold = 1
+	downMP.breaker.state = breakerOpen
+	downMP.breaker.openedAt = time.Now()
+
+	up := &fakeProvider{name: "up", price: 200.0}
+
+	f := &Fetcher{providers: []*managedProvider{downMP, newTestManagedProvider(up)}}
+
+	price, err := f.fetchStockPriceWithContext(context.Background(), "AAPL")
+	if err != nil {
+		t.Fatalf("fetchStockPriceWithContext returned error: %v", err)
+	}
+	if price != 200.0 {
+		t.Errorf("price = %v, want 200.0 from the only provider with a closed breaker", price)
+	}
+	if down.calls != 0 {
+		t.Errorf("down provider was called %d times, want 0 since its breaker is open", down.calls)
+	}
+}
@@ -0,0 +1,389 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// doJSON performs a GET against url and decodes the JSON body into out,
+// classifying the failure as retriable (network error, timeout, 5xx, 429)
+// or not (anything else, e.g. a 4xx for an unknown symbol) so callers can
+// build a *ProviderError without repeating this logic five times.
+func doJSON(ctx context.Context, client *http.Client, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return &ProviderError{Err: err, Retriable: false}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return &ProviderError{Err: err, Retriable: true}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return &ProviderError{Err: fmt.Errorf("http %d", resp.StatusCode), Retriable: true}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &ProviderError{Err: fmt.Errorf("http %d", resp.StatusCode), Retriable: false}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &ProviderError{Err: err, Retriable: true}
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return &ProviderError{Err: err, Retriable: false}
+	}
+	return nil
+}
+
+// --- Alpha Vantage -----------------------------------------------------
+
+const defaultAlphaVantageBaseURL = "https://www.alphavantage.co/query"
+
+type alphaVantageProvider struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+func newAlphaVantageProvider(cfg ProviderConfig, client *http.Client) *alphaVantageProvider {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = defaultAlphaVantageBaseURL
+	}
+	return &alphaVantageProvider{apiKey: cfg.APIKey, baseURL: baseURL, client: client}
+}
+
+func (p *alphaVantageProvider) Name() string { return "alphavantage" }
+
+type alphaVantageQuoteResponse struct {
+	GlobalQuote struct {
+		Symbol string `json:"01. symbol"`
+		Price  string `json:"05. price"`
+	} `json:"Global Quote"`
+}
+
+func (p *alphaVantageProvider) FetchQuote(ctx context.Context, symbol string) (Quote, error) {
+	url := fmt.Sprintf("%s?function=GLOBAL_QUOTE&symbol=%s&apikey=%s", p.baseURL, symbol, p.apiKey)
+
+	var result alphaVantageQuoteResponse
+	if err := doJSON(ctx, p.client, url, &result); err != nil {
+		err.(*ProviderError).Provider = p.Name()
+		return Quote{}, err
+	}
+	if result.GlobalQuote.Price == "" {
+		return Quote{}, &ProviderError{Provider: p.Name(), Err: fmt.Errorf("no price data for %s", symbol), Retriable: true}
+	}
+
+	price, err := parsePrice(result.GlobalQuote.Price)
+	if err != nil {
+		return Quote{}, &ProviderError{Provider: p.Name(), Err: err, Retriable: false}
+	}
+	return Quote{Symbol: symbol, Price: price}, nil
+}
+
+type alphaVantageHistoricalResponse struct {
+	TimeSeriesDaily map[string]struct {
+		Close string `json:"4. close"`
+	} `json:"Time Series (Daily)"`
+}
+
+func (p *alphaVantageProvider) FetchHistorical(ctx context.Context, symbol string, from, to time.Time) ([]HistoricalPoint, error) {
+	url := fmt.Sprintf("%s?function=TIME_SERIES_DAILY&symbol=%s&apikey=%s", p.baseURL, symbol, p.apiKey)
+
+	var result alphaVantageHistoricalResponse
+	if err := doJSON(ctx, p.client, url, &result); err != nil {
+		err.(*ProviderError).Provider = p.Name()
+		return nil, err
+	}
+
+	var points []HistoricalPoint
+	for date, bar := range result.TimeSeriesDaily {
+		timestamp, err := time.Parse("2006-01-02", date)
+		if err != nil || timestamp.Before(from) || timestamp.After(to) {
+			continue
+		}
+		close, err := parsePrice(bar.Close)
+		if err != nil {
+			continue
+		}
+		points = append(points, HistoricalPoint{Timestamp: timestamp, Close: close})
+	}
+	return points, nil
+}
+
+// --- IEX Cloud -----------------------------------------------------------
+
+const defaultIEXBaseURL = "https://cloud.iexapis.com/stable"
+
+type iexCloudProvider struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+func newIEXCloudProvider(cfg ProviderConfig, client *http.Client) *iexCloudProvider {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = defaultIEXBaseURL
+	}
+	return &iexCloudProvider{apiKey: cfg.APIKey, baseURL: baseURL, client: client}
+}
+
+func (p *iexCloudProvider) Name() string { return "iex" }
+
+type iexQuoteResponse struct {
+	LatestPrice float64 `json:"latestPrice"`
+}
+
+func (p *iexCloudProvider) FetchQuote(ctx context.Context, symbol string) (Quote, error) {
+	url := fmt.Sprintf("%s/stock/%s/quote?token=%s", p.baseURL, symbol, p.apiKey)
+
+	var result iexQuoteResponse
+	if err := doJSON(ctx, p.client, url, &result); err != nil {
+		err.(*ProviderError).Provider = p.Name()
+		return Quote{}, err
+	}
+	return Quote{Symbol: symbol, Price: result.LatestPrice}, nil
+}
+
+type iexHistoricalBar struct {
+	Date  string  `json:"date"`
+	Close float64 `json:"close"`
+}
+
+func (p *iexCloudProvider) FetchHistorical(ctx context.Context, symbol string, from, to time.Time) ([]HistoricalPoint, error) {
+	url := fmt.Sprintf("%s/stock/%s/chart/1y?token=%s", p.baseURL, symbol, p.apiKey)
+
+	var bars []iexHistoricalBar
+	if err := doJSON(ctx, p.client, url, &bars); err != nil {
+		err.(*ProviderError).Provider = p.Name()
+		return nil, err
+	}
+
+	points := make([]HistoricalPoint, 0, len(bars))
+	for _, bar := range bars {
+		timestamp, err := time.Parse("2006-01-02", bar.Date)
+		if err != nil || timestamp.Before(from) || timestamp.After(to) {
+			continue
+		}
+		points = append(points, HistoricalPoint{Timestamp: timestamp, Close: bar.Close})
+	}
+	return points, nil
+}
+
+// --- Finnhub ---------------------------------------------------------------
+
+const defaultFinnhubBaseURL = "https://finnhub.io/api/v1"
+
+type finnhubProvider struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+func newFinnhubProvider(cfg ProviderConfig, client *http.Client) *finnhubProvider {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = defaultFinnhubBaseURL
+	}
+	return &finnhubProvider{apiKey: cfg.APIKey, baseURL: baseURL, client: client}
+}
+
+func (p *finnhubProvider) Name() string { return "finnhub" }
+
+type finnhubQuoteResponse struct {
+	Current float64 `json:"c"`
+}
+
+func (p *finnhubProvider) FetchQuote(ctx context.Context, symbol string) (Quote, error) {
+	url := fmt.Sprintf("%s/quote?symbol=%s&token=%s", p.baseURL, symbol, p.apiKey)
+
+	var result finnhubQuoteResponse
+	if err := doJSON(ctx, p.client, url, &result); err != nil {
+		err.(*ProviderError).Provider = p.Name()
+		return Quote{}, err
+	}
+	if result.Current == 0 {
+		return Quote{}, &ProviderError{Provider: p.Name(), Err: fmt.Errorf("no price data for %s", symbol), Retriable: true}
+	}
+	return Quote{Symbol: symbol, Price: result.Current}, nil
+}
+
+type finnhubCandleResponse struct {
+	Close  []float64 `json:"c"`
+	Time   []int64   `json:"t"`
+	Status string    `json:"s"`
+}
+
+func (p *finnhubProvider) FetchHistorical(ctx context.Context, symbol string, from, to time.Time) ([]HistoricalPoint, error) {
+	url := fmt.Sprintf("%s/stock/candle?symbol=%s&resolution=D&from=%d&to=%d&token=%s",
+		p.baseURL, symbol, from.Unix(), to.Unix(), p.apiKey)
+
+	var result finnhubCandleResponse
+	if err := doJSON(ctx, p.client, url, &result); err != nil {
+		err.(*ProviderError).Provider = p.Name()
+		return nil, err
+	}
+	if result.Status != "ok" {
+		return nil, &ProviderError{Provider: p.Name(), Err: fmt.Errorf("no candles for %s", symbol), Retriable: true}
+	}
+
+	points := make([]HistoricalPoint, 0, len(result.Close))
+	for i := range result.Close {
+		points = append(points, HistoricalPoint{
+			Timestamp: time.Unix(result.Time[i], 0).UTC(),
+			Close:     result.Close[i],
+		})
+	}
+	return points, nil
+}
+
+// --- Yahoo Finance (unofficial chart API) ----------------------------------
+
+const defaultYahooBaseURL = "https://query1.finance.yahoo.com/v8/finance/chart"
+
+// yahooFinanceProvider uses Yahoo's unofficial, keyless chart endpoint, so
+// it's useful mainly as a free last-resort fallback rather than a primary
+// source.
+type yahooFinanceProvider struct {
+	baseURL string
+	client  *http.Client
+}
+
+func newYahooFinanceProvider(cfg ProviderConfig, client *http.Client) *yahooFinanceProvider {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = defaultYahooBaseURL
+	}
+	return &yahooFinanceProvider{baseURL: baseURL, client: client}
+}
+
+func (p *yahooFinanceProvider) Name() string { return "yahoo" }
+
+type yahooChartResponse struct {
+	Chart struct {
+		Result []struct {
+			Meta struct {
+				RegularMarketPrice float64 `json:"regularMarketPrice"`
+			} `json:"meta"`
+			Timestamp  []int64 `json:"timestamp"`
+			Indicators struct {
+				Quote []struct {
+					Close []float64 `json:"close"`
+				} `json:"quote"`
+			} `json:"indicators"`
+		} `json:"result"`
+	} `json:"chart"`
+}
+
+func (p *yahooFinanceProvider) FetchQuote(ctx context.Context, symbol string) (Quote, error) {
+	url := fmt.Sprintf("%s/%s", p.baseURL, symbol)
+
+	var result yahooChartResponse
+	if err := doJSON(ctx, p.client, url, &result); err != nil {
+		err.(*ProviderError).Provider = p.Name()
+		return Quote{}, err
+	}
+	if len(result.Chart.Result) == 0 {
+		return Quote{}, &ProviderError{Provider: p.Name(), Err: fmt.Errorf("no chart data for %s", symbol), Retriable: true}
+	}
+	return Quote{Symbol: symbol, Price: result.Chart.Result[0].Meta.RegularMarketPrice}, nil
+}
+
+func (p *yahooFinanceProvider) FetchHistorical(ctx context.Context, symbol string, from, to time.Time) ([]HistoricalPoint, error) {
+	url := fmt.Sprintf("%s/%s?period1=%d&period2=%d&interval=1d", p.baseURL, symbol, from.Unix(), to.Unix())
+
+	var result yahooChartResponse
+	if err := doJSON(ctx, p.client, url, &result); err != nil {
+		err.(*ProviderError).Provider = p.Name()
+		return nil, err
+	}
+	if len(result.Chart.Result) == 0 || len(result.Chart.Result[0].Indicators.Quote) == 0 {
+		return nil, &ProviderError{Provider: p.Name(), Err: fmt.Errorf("no chart data for %s", symbol), Retriable: true}
+	}
+
+	r := result.Chart.Result[0]
+	closes := r.Indicators.Quote[0].Close
+	points := make([]HistoricalPoint, 0, len(r.Timestamp))
+	for i, ts := range r.Timestamp {
+		if i >= len(closes) {
+			break
+		}
+		points = append(points, HistoricalPoint{Timestamp: time.Unix(ts, 0).UTC(), Close: closes[i]})
+	}
+	return points, nil
+}
+
+// --- Polygon.io --------------------------------------------------------
+
+const defaultPolygonBaseURL = "https://api.polygon.io"
+
+type polygonProvider struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+func newPolygonProvider(cfg ProviderConfig, client *http.Client) *polygonProvider {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = defaultPolygonBaseURL
+	}
+	return &polygonProvider{apiKey: cfg.APIKey, baseURL: baseURL, client: client}
+}
+
+func (p *polygonProvider) Name() string { return "polygon" }
+
+type polygonLastTradeResponse struct {
+	Results struct {
+		Price float64 `json:"p"`
+	} `json:"results"`
+}
+
+func (p *polygonProvider) FetchQuote(ctx context.Context, symbol string) (Quote, error) {
+	url := fmt.Sprintf("%s/v2/last/trade/%s?apiKey=%s", p.baseURL, symbol, p.apiKey)
+
+	var result polygonLastTradeResponse
+	if err := doJSON(ctx, p.client, url, &result); err != nil {
+		err.(*ProviderError).Provider = p.Name()
+		return Quote{}, err
+	}
+	if result.Results.Price == 0 {
+		return Quote{}, &ProviderError{Provider: p.Name(), Err: fmt.Errorf("no trade data for %s", symbol), Retriable: true}
+	}
+	return Quote{Symbol: symbol, Price: result.Results.Price}, nil
+}
+
+type polygonAggsResponse struct {
+	Results []struct {
+		Close     float64 `json:"c"`
+		Timestamp int64   `json:"t"` // milliseconds since epoch
+	} `json:"results"`
+}
+
+func (p *polygonProvider) FetchHistorical(ctx context.Context, symbol string, from, to time.Time) ([]HistoricalPoint, error) {
+	url := fmt.Sprintf("%s/v2/aggs/ticker/%s/range/1/day/%s/%s?apiKey=%s",
+		p.baseURL, symbol, from.Format("2006-01-02"), to.Format("2006-01-02"), p.apiKey)
+
+	var result polygonAggsResponse
+	if err := doJSON(ctx, p.client, url, &result); err != nil {
+		err.(*ProviderError).Provider = p.Name()
+		return nil, err
+	}
+
+	points := make([]HistoricalPoint, 0, len(result.Results))
+	for _, bar := range result.Results {
+		points = append(points, HistoricalPoint{
+			Timestamp: time.UnixMilli(bar.Timestamp).UTC(),
+			Close:     bar.Close,
+		})
+	}
+	return points, nil
+}
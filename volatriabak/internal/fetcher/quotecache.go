@@ -0,0 +1,94 @@
+package fetcher
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// defaultQuoteCacheTTL is how long a cached quote is served without
+// triggering even a background refresh.
+const defaultQuoteCacheTTL = 30 * time.Second
+
+type quoteCacheEntry struct {
+	price     float64
+	fetchedAt time.Time
+}
+
+// quoteCache sits in front of GetQuote's call into fetchStockPriceWithContext,
+// keyed by symbol. A fresh entry is returned directly; a stale one is still
+// returned immediately (stale-while-revalidate) while a background call
+// refreshes it, so a slow or unhealthy provider never blocks a reader on a
+// symbol that was fetched recently. A genuine miss coalesces concurrent
+// callers for the same symbol into one upstream call via singleflight,
+// which matters when the fetcher's own poll loop and a direct GetQuote call
+// land on the same tick.
+type quoteCache struct {
+	mu      sync.RWMutex
+	entries map[string]quoteCacheEntry
+	ttl     time.Duration
+	group   singleflight.Group
+	metrics *FetcherMetrics
+}
+
+func newQuoteCache(ttl time.Duration, metrics *FetcherMetrics) *quoteCache {
+	if ttl <= 0 {
+		ttl = defaultQuoteCacheTTL
+	}
+	return &quoteCache{
+		entries: make(map[string]quoteCacheEntry),
+		ttl:     ttl,
+		metrics: metrics,
+	}
+}
+
+// Get returns symbol's cached price, calling fetch on a miss or to
+// refresh a stale entry in the background.
+func (c *quoteCache) Get(symbol string, fetch func() (float64, error)) (float64, error) {
+	c.mu.RLock()
+	entry, ok := c.entries[symbol]
+	c.mu.RUnlock()
+
+	if ok {
+		c.metrics.recordCacheHit()
+		if time.Since(entry.fetchedAt) >= c.ttl {
+			go c.refresh(symbol, fetch)
+		}
+		return entry.price, nil
+	}
+
+	c.metrics.recordCacheMiss()
+	v, err, shared := c.group.Do(symbol, func() (interface{}, error) {
+		price, err := fetch()
+		if err != nil {
+			return nil, err
+		}
+		c.set(symbol, price)
+		return price, nil
+	})
+	if shared {
+		c.metrics.recordCacheCoalesced()
+	}
+	if err != nil {
+		return 0, err
+	}
+	return v.(float64), nil
+}
+
+func (c *quoteCache) refresh(symbol string, fetch func() (float64, error)) {
+	c.group.Do(symbol, func() (interface{}, error) {
+		price, err := fetch()
+		if err != nil {
+			return nil, err
+		}
+		c.set(symbol, price)
+		return price, nil
+	})
+}
+
+func (c *quoteCache) set(symbol string, price float64) {
+	c.mu.Lock()
+	c.entries[symbol] = quoteCacheEntry{price: price, fetchedAt: time.Now()}
+	c.mu.Unlock()
+}
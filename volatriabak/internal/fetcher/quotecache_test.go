@@ -0,0 +1,113 @@
+package fetcher
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestQuoteCacheGetCoalescesConcurrentMissesIntoOneFetch(t *testing.T) {
+	c := newQuoteCache(time.Minute, &FetcherMetrics{})
+
+	var calls int64
+	release := make(chan struct{})
+	fetch := func() (float64, error) {
+		atomic.AddInt64(&calls, 1)
+		<-release
+		return 100.0, nil
+	}
+
+	const callers = 10
+	var wg sync.WaitGroup
+	results := make([]float64, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			price, err := c.Get("AAPL", fetch)
+			if err != nil {
+				t.Errorf("Get: %v", err)
+				return
+			}
+			results[i] = price
+		}(i)
+	}
+
+	// Give every goroutine a chance to reach group.Do before releasing the
+	// shared fetch, so they're actually coalesced rather than serialized.
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt64(&calls); got != 1 {
+		t.Errorf("fetch was called %d times for %d concurrent misses on the same symbol, want exactly 1", got, callers)
+	}
+	for i, price := range results {
+		if price != 100.0 {
+			t.Errorf("caller %d got price %v, want 100.0", i, price)
+		}
+	}
+}
+
+func TestQuoteCacheGetReturnsFreshEntryWithoutRefetching(t *testing.T) {
+	c := newQuoteCache(time.Minute, &FetcherMetrics{})
+
+	calls := 0
+	fetch := func() (float64, error) {
+		calls++
+		return 100.0, nil
+	}
+
+	if _, err := c.Get("AAPL", fetch); err != nil {
+		t.Fatalf("Get (miss): %v", err)
+	}
+	price, err := c.Get("AAPL", fetch)
+	if err != nil {
+		t.Fatalf("Get (hit): %v", err)
+	}
+	if price != 100.0 {
+		t.Errorf("price = %v, want 100.0", price)
+	}
+	if calls != 1 {
+		t.Errorf("fetch was called %d times, want exactly 1 (second Get should hit the fresh cache entry)", calls)
+	}
+}
+
+func TestQuoteCacheGetRefreshesStaleEntryInBackgroundButReturnsImmediately(t *testing.T) {
+	c := newQuoteCache(10*time.Millisecond, &FetcherMetrics{})
+
+	if _, err := c.Get("AAPL", func() (float64, error) { return 100.0, nil }); err != nil {
+		t.Fatalf("Get (initial): %v", err)
+	}
+	time.Sleep(50 * time.Millisecond) // let the entry go stale
+
+	refreshed := make(chan struct{})
+	price, err := c.Get("AAPL", func() (float64, error) {
+		defer close(refreshed)
+		return 200.0, nil
+	})
+	if err != nil {
+		t.Fatalf("Get (stale): %v", err)
+	}
+	if price != 100.0 {
+		t.Errorf("stale-while-revalidate should return the old price 100.0 immediately, got %v", price)
+	}
+
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("background refresh never ran")
+	}
+	// refreshed fires as fetch returns, just before refresh stores the new
+	// price; joining the same singleflight key waits for that store.
+	c.group.Do("AAPL", func() (interface{}, error) { return nil, nil })
+
+	price, err = c.Get("AAPL", func() (float64, error) { return 300.0, nil })
+	if err != nil {
+		t.Fatalf("Get (post-refresh): %v", err)
+	}
+	if price != 200.0 {
+		t.Errorf("price after background refresh completed = %v, want 200.0", price)
+	}
+}
@@ -0,0 +1,141 @@
+// Package stream fans out price ticks to WebSocket and SSE subscribers, so
+// clients can follow a symbol without polling the REST endpoints.
+package stream
+
+import (
+	"sync"
+	"time"
+)
+
+// Tick is a single price update published to subscribers of a symbol.
+type Tick struct {
+	Symbol    string    `json:"symbol"`
+	Price     float64   `json:"price"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// subscriberQueueDepth bounds how many pending ticks a slow subscriber can
+// accumulate before the oldest one is dropped to make room for the newest.
+const subscriberQueueDepth = 32
+
+// Subscriber is a single connected client (WebSocket or SSE). Send delivers
+// ticks for whatever symbols the client is currently subscribed to; the
+// transport goroutine reading Send is responsible for writing them out.
+type Subscriber struct {
+	Send chan Tick
+
+	mu      sync.Mutex
+	symbols map[string]bool
+}
+
+// NewSubscriber returns a Subscriber with no symbols followed yet.
+func NewSubscriber() *Subscriber {
+	return &Subscriber{
+		Send:    make(chan Tick, subscriberQueueDepth),
+		symbols: make(map[string]bool),
+	}
+}
+
+func (s *Subscriber) addSymbols(symbols []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sym := range symbols {
+		s.symbols[sym] = true
+	}
+}
+
+func (s *Subscriber) removeSymbols(symbols []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sym := range symbols {
+		delete(s.symbols, sym)
+	}
+}
+
+// enqueue delivers tick to the subscriber without blocking the publisher:
+// if the subscriber's queue is full, the oldest pending tick is dropped to
+// make room, so one slow consumer never stalls the hub.
+func (s *Subscriber) enqueue(tick Tick) {
+	select {
+	case s.Send <- tick:
+		return
+	default:
+	}
+
+	select {
+	case <-s.Send:
+	default:
+	}
+
+	select {
+	case s.Send <- tick:
+	default:
+		// Another publish raced us and refilled the queue; drop this tick
+		// rather than block.
+	}
+}
+
+// Hub fans out published ticks to every subscriber currently following the
+// tick's symbol.
+type Hub struct {
+	mu     sync.RWMutex
+	topics map[string]map[*Subscriber]struct{}
+}
+
+// NewHub returns an empty Hub.
+func NewHub() *Hub {
+	return &Hub{topics: make(map[string]map[*Subscriber]struct{})}
+}
+
+// Subscribe adds sub to the given symbols' topics.
+func (h *Hub) Subscribe(sub *Subscriber, symbols []string) {
+	sub.addSymbols(symbols)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, sym := range symbols {
+		if h.topics[sym] == nil {
+			h.topics[sym] = make(map[*Subscriber]struct{})
+		}
+		h.topics[sym][sub] = struct{}{}
+	}
+}
+
+// Unsubscribe removes sub from the given symbols' topics, leaving any other
+// symbols it follows untouched.
+func (h *Hub) Unsubscribe(sub *Subscriber, symbols []string) {
+	sub.removeSymbols(symbols)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, sym := range symbols {
+		delete(h.topics[sym], sub)
+		if len(h.topics[sym]) == 0 {
+			delete(h.topics, sym)
+		}
+	}
+}
+
+// Remove drops sub from every topic it was subscribed to. Call this when a
+// connection closes.
+func (h *Hub) Remove(sub *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sym, subs := range h.topics {
+		delete(subs, sub)
+		if len(subs) == 0 {
+			delete(h.topics, sym)
+		}
+	}
+}
+
+// Publish delivers tick to every subscriber currently following its symbol.
+// Delivery is non-blocking per subscriber (see Subscriber.enqueue), so one
+// slow client never backs up the writer that's storing new prices.
+func (h *Hub) Publish(tick Tick) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for sub := range h.topics[tick.Symbol] {
+		sub.enqueue(tick)
+	}
+}
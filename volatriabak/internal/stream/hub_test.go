@@ -0,0 +1,113 @@
+package stream
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHubPublishDeliversOnlyToSubscribersOfSymbol(t *testing.T) {
+	h := NewHub()
+	aapl := NewSubscriber()
+	msft := NewSubscriber()
+
+	h.Subscribe(aapl, []string{"AAPL"})
+	h.Subscribe(msft, []string{"MSFT"})
+
+	h.Publish(Tick{Symbol: "AAPL", Price: 100})
+
+	select {
+	case tick := <-aapl.Send:
+		if tick.Price != 100 {
+			t.Errorf("aapl received price %v, want 100", tick.Price)
+		}
+	default:
+		t.Fatal("AAPL subscriber should have received the AAPL tick")
+	}
+
+	select {
+	case tick := <-msft.Send:
+		t.Fatalf("MSFT subscriber should not have received an AAPL tick, got %+v", tick)
+	default:
+	}
+}
+
+func TestHubUnsubscribeStopsDelivery(t *testing.T) {
+	h := NewHub()
+	sub := NewSubscriber()
+	h.Subscribe(sub, []string{"AAPL"})
+	h.Unsubscribe(sub, []string{"AAPL"})
+
+	h.Publish(Tick{Symbol: "AAPL", Price: 100})
+
+	select {
+	case tick := <-sub.Send:
+		t.Fatalf("unsubscribed subscriber should not have received a tick, got %+v", tick)
+	default:
+	}
+}
+
+func TestHubRemoveDropsSubscriberFromEveryTopic(t *testing.T) {
+	h := NewHub()
+	sub := NewSubscriber()
+	h.Subscribe(sub, []string{"AAPL", "MSFT"})
+
+	h.Remove(sub)
+
+	h.Publish(Tick{Symbol: "AAPL", Price: 100})
+	h.Publish(Tick{Symbol: "MSFT", Price: 200})
+
+	select {
+	case tick := <-sub.Send:
+		t.Fatalf("removed subscriber should not receive any tick, got %+v", tick)
+	default:
+	}
+}
+
+func TestSubscriberEnqueueDropsOldestWhenQueueIsFull(t *testing.T) {
+	sub := NewSubscriber()
+
+	for i := 0; i < subscriberQueueDepth; i++ {
+		sub.enqueue(Tick{Symbol: "AAPL", Price: float64(i)})
+	}
+	// The queue is now full of prices 0..31; one more enqueue should drop
+	// the oldest (0) to make room for the newest (32).
+	sub.enqueue(Tick{Symbol: "AAPL", Price: float64(subscriberQueueDepth)})
+
+	first := <-sub.Send
+	if first.Price != 1 {
+		t.Errorf("oldest tick after overflow = %v, want 1 (price 0 should have been dropped)", first.Price)
+	}
+
+	var last Tick
+	for {
+		select {
+		case last = <-sub.Send:
+			continue
+		default:
+		}
+		break
+	}
+	if last.Price != float64(subscriberQueueDepth) {
+		t.Errorf("newest queued tick = %v, want %v", last.Price, subscriberQueueDepth)
+	}
+}
+
+func TestHubPublishIsNonBlockingForSlowSubscriber(t *testing.T) {
+	h := NewHub()
+	slow := NewSubscriber()
+	h.Subscribe(slow, []string{"AAPL"})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < subscriberQueueDepth*2; i++ {
+			h.Publish(Tick{Symbol: "AAPL", Price: float64(i)})
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish blocked on a subscriber that never drained its queue")
+	}
+}
@@ -0,0 +1,54 @@
+package stream
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const sseHeartbeatInterval = 30 * time.Second
+
+// ServeSSE streams ticks for GET /sse/prices?symbols=AAPL,MSFT as
+// Server-Sent Events, for clients that can't use the WebSocket endpoint.
+// Unlike ServeWS, the symbol set is fixed for the life of the connection
+// since SSE has no client-to-server control channel.
+func ServeSSE(hub *Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sub := NewSubscriber()
+		hub.Subscribe(sub, parseSymbols(c.Query("symbols")))
+		defer hub.Remove(sub)
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+
+		heartbeat := time.NewTicker(sseHeartbeatInterval)
+		defer heartbeat.Stop()
+
+		clientGone := c.Request.Context().Done()
+
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case tick, ok := <-sub.Send:
+				if !ok {
+					return false
+				}
+				payload, err := json.Marshal(tick)
+				if err != nil {
+					return true
+				}
+				w.Write([]byte("data: "))
+				w.Write(payload)
+				w.Write([]byte("\n\n"))
+				return true
+			case <-heartbeat.C:
+				w.Write([]byte(": heartbeat\n\n"))
+				return true
+			case <-clientGone:
+				return false
+			}
+		})
+	}
+}
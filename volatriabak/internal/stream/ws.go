@@ -0,0 +1,134 @@
+package stream
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	pingInterval = 30 * time.Second
+	pongWait     = 60 * time.Second
+	writeWait    = 10 * time.Second
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// Volatria is served cross-origin from the configured frontend; origin
+	// enforcement happens at the CORS layer for the REST API, so the socket
+	// upgrade itself stays permissive.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// controlFrame is the JSON control protocol clients send to change which
+// symbols they follow on an already-open connection, e.g.
+// {"action":"subscribe","symbols":["AAPL","MSFT"]}.
+type controlFrame struct {
+	Action  string   `json:"action"`
+	Symbols []string `json:"symbols"`
+}
+
+func parseSymbols(raw string) []string {
+	if raw == "" {
+		return nil
+	}
+	parts := strings.Split(raw, ",")
+	symbols := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			symbols = append(symbols, strings.ToUpper(p))
+		}
+	}
+	return symbols
+}
+
+// ServeWS upgrades GET /ws/prices?symbols=AAPL,MSFT to a WebSocket, streams
+// ticks for the requested symbols, and accepts subscribe/unsubscribe
+// control frames to change them without reconnecting.
+func ServeWS(hub *Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		sub := NewSubscriber()
+		hub.Subscribe(sub, parseSymbols(c.Query("symbols")))
+		defer hub.Remove(sub)
+
+		done := make(chan struct{})
+		go readControlFrames(conn, hub, sub, done)
+
+		writeLoop(conn, sub, done)
+	}
+}
+
+func readControlFrames(conn *websocket.Conn, hub *Hub, sub *Subscriber, done chan struct{}) {
+	defer close(done)
+
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		conn.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var frame controlFrame
+		if err := json.Unmarshal(message, &frame); err != nil {
+			continue
+		}
+
+		switch frame.Action {
+		case "subscribe":
+			hub.Subscribe(sub, normalizeSymbols(frame.Symbols))
+		case "unsubscribe":
+			hub.Unsubscribe(sub, normalizeSymbols(frame.Symbols))
+		}
+	}
+}
+
+func normalizeSymbols(symbols []string) []string {
+	normalized := make([]string, 0, len(symbols))
+	for _, s := range symbols {
+		if s = strings.ToUpper(strings.TrimSpace(s)); s != "" {
+			normalized = append(normalized, s)
+		}
+	}
+	return normalized
+}
+
+func writeLoop(conn *websocket.Conn, sub *Subscriber, done chan struct{}) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case tick, ok := <-sub.Send:
+			if !ok {
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteJSON(tick); err != nil {
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
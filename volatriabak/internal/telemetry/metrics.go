@@ -0,0 +1,154 @@
+// Package telemetry centralizes the Prometheus collectors and OpenTelemetry
+// tracing setup shared by the api and database packages, so neither has to
+// carry its own ad-hoc counters or wire an exporter independently.
+package telemetry
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics bundles every Prometheus collector Volatria exports, registered
+// against a dedicated registry (rather than the global default) so a
+// process can hold more than one Metrics instance, e.g. in tests.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	HTTPRequestDuration   *prometheus.HistogramVec
+	DBQueryDuration       *prometheus.HistogramVec
+	CacheHits             *prometheus.CounterVec
+	CacheMisses           *prometheus.CounterVec
+	CircuitBreakerState   *prometheus.GaugeVec
+	RateLimiterRejections *prometheus.CounterVec
+	DBConnectionsInUse    prometheus.Gauge
+	DBConnectionsIdle     prometheus.Gauge
+
+	// Fetcher collectors. CacheHits/CacheMisses above already cover the api
+	// package's response cache; the fetcher's own quote cache (see
+	// fetcher.FetcherMetrics) isn't exported here since it's tracked
+	// in-process rather than per-tier.
+	ProviderRequestDuration *prometheus.HistogramVec
+	ProviderRetries         *prometheus.CounterVec
+	ProviderRateLimitWait   *prometheus.HistogramVec
+	DBWriteDuration         *prometheus.HistogramVec
+	BackfillQueueDepth      *prometheus.GaugeVec
+}
+
+// New builds a Metrics with every collector registered, ready to be wired
+// into the api and database packages and served via Handler.
+func New() *Metrics {
+	registry := prometheus.NewRegistry()
+
+	m := &Metrics{
+		registry: registry,
+		HTTPRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "volatria",
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency in seconds, by route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		DBQueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "volatria",
+			Subsystem: "db",
+			Name:      "query_duration_seconds",
+			Help:      "Database query latency in seconds, by query kind.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"query"}),
+		CacheHits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "volatria",
+			Subsystem: "cache",
+			Name:      "hits_total",
+			Help:      "Cache hits, by tier (local/remote).",
+		}, []string{"tier"}),
+		CacheMisses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "volatria",
+			Subsystem: "cache",
+			Name:      "misses_total",
+			Help:      "Cache misses, by tier (local/remote).",
+		}, []string{"tier"}),
+		CircuitBreakerState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "volatria",
+			Subsystem: "circuit_breaker",
+			Name:      "state",
+			Help:      "Circuit breaker state by name (0=closed, 1=open).",
+		}, []string{"name"}),
+		RateLimiterRejections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "volatria",
+			Subsystem: "rate_limiter",
+			Name:      "rejections_total",
+			Help:      "Requests rejected by the rate limiter, by algorithm and key kind.",
+		}, []string{"algorithm", "key_kind"}),
+		DBConnectionsInUse: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "volatria",
+			Subsystem: "db",
+			Name:      "connections_in_use",
+			Help:      "Database connections currently checked out of the pool.",
+		}),
+		DBConnectionsIdle: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "volatria",
+			Subsystem: "db",
+			Name:      "connections_idle",
+			Help:      "Database connections currently idle in the pool.",
+		}),
+		ProviderRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "volatria",
+			Subsystem: "fetcher",
+			Name:      "provider_request_duration_seconds",
+			Help:      "Upstream data provider request latency in seconds, by provider.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"provider"}),
+		ProviderRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "volatria",
+			Subsystem: "fetcher",
+			Name:      "provider_retries_total",
+			Help:      "Failovers to the next configured provider, by the provider that failed.",
+		}, []string{"provider"}),
+		ProviderRateLimitWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "volatria",
+			Subsystem: "fetcher",
+			Name:      "provider_rate_limit_wait_seconds",
+			Help:      "Time spent waiting on a provider's rate limiter before a request, by provider.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"provider"}),
+		DBWriteDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "volatria",
+			Subsystem: "fetcher",
+			Name:      "db_write_duration_seconds",
+			Help:      "Latency of the fetcher's writes back into the database, by operation.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"op"}),
+		BackfillQueueDepth: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "volatria",
+			Subsystem: "fetcher",
+			Name:      "backfill_queue_depth",
+			Help:      "Backfill jobs currently queued, by status (pending/running).",
+		}, []string{"status"}),
+	}
+
+	registry.MustRegister(
+		m.HTTPRequestDuration,
+		m.DBQueryDuration,
+		m.CacheHits,
+		m.CacheMisses,
+		m.CircuitBreakerState,
+		m.RateLimiterRejections,
+		m.DBConnectionsInUse,
+		m.DBConnectionsIdle,
+		m.ProviderRequestDuration,
+		m.ProviderRetries,
+		m.ProviderRateLimitWait,
+		m.DBWriteDuration,
+		m.BackfillQueueDepth,
+	)
+
+	return m
+}
+
+// Handler returns the http.Handler that serves this Metrics' collectors in
+// the Prometheus exposition format, meant to be mounted at GET /metrics.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
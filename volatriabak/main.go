@@ -1,49 +1,154 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/klea/volatria/volatria/internal/api"
+	apiauth "github.com/klea/volatria/volatria/internal/auth"
 	"github.com/klea/volatria/volatria/internal/database"
 	"github.com/klea/volatria/volatria/internal/fetcher"
+	"github.com/klea/volatria/volatria/internal/stream"
+	"github.com/klea/volatria/volatria/internal/telemetry"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
+// shutdownTimeout bounds how long graceful shutdown waits for in-flight HTTP
+// requests to finish before the server is torn down anyway.
+const shutdownTimeout = 15 * time.Second
+
+// anonymousRPS/Burst is the default per-IP quota; authenticated users get a
+// higher per-user quota set on login (see api.WithRateLimiter).
+const (
+	anonymousRPS   = 5
+	anonymousBurst = 10
+)
+
+func newAuthManager() *apiauth.Manager {
+	signingKey := os.Getenv("VOLATRIA_JWT_SIGNING_KEY")
+	if signingKey == "" {
+		// Fine for local development; production deployments must set
+		// VOLATRIA_JWT_SIGNING_KEY so tokens survive a restart.
+		signingKey = "dev-only-signing-key-do-not-use-in-production"
+	}
+
+	keyring := apiauth.NewKeyring(apiauth.KeyringConfig{
+		ActiveKid: "default",
+		Keys:      map[string][]byte{"default": []byte(signingKey)},
+	})
+
+	return apiauth.NewManager(keyring, apiauth.Config{
+		Issuer:    "volatria",
+		AccessTTL: 15 * time.Minute,
+	})
+}
+
 func main() {
+	// metrics backs every Prometheus collector exported at /metrics; tracing
+	// is a no-op unless VOLATRIA_OTLP_ENDPOINT points at a collector.
+	metrics := telemetry.New()
+	shutdownTracer, err := telemetry.InitTracer("volatria-api")
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+
+	// ctx is cancelled on SIGINT/SIGTERM; it's the root context for every
+	// background goroutine below, so a shutdown signal stops the fetcher's
+	// poll loop and the backfiller's queue loop directly instead of relying
+	// solely on their Stop methods.
+	ctx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
 	// Initialize database
 	db, err := database.New()
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
+	db.SetMetrics(metrics)
 
 	// Initialize fetcher
-	stockFetcher := fetcher.New(db)
-	stockFetcher.Start()
-	defer stockFetcher.Stop()
+	stockFetcher := fetcher.New(db, nil)
+	stockFetcher.SetMetrics(metrics)
+	if err := stockFetcher.Start(ctx); err != nil {
+		log.Fatalf("Failed to start fetcher: %v", err)
+	}
+
+	// The Backfiller owns historical ingestion: it seeds a daily job per
+	// active symbol (from the symbols table, not a hardcoded slice) and
+	// resumes each from its last high-water mark rather than re-pulling a
+	// year of data on every restart.
+	backfiller := fetcher.NewBackfiller(db, stockFetcher, 10*time.Second)
+	backfiller.SetMetrics(metrics)
+	if err := backfiller.Start(ctx); err != nil {
+		log.Fatalf("Failed to start backfiller: %v", err)
+	}
 
-	// Initialize API handlers
-	handler := api.New(db)
+	// Wire the price hub so every stored tick (whether from the fetcher's
+	// poll loop or a historical backfill) fans out to WebSocket/SSE
+	// subscribers without the database package depending on stream.
+	priceHub := stream.NewHub()
+	db.SetWriteHook(func(symbol string, price float64, timestamp time.Time) {
+		priceHub.Publish(stream.Tick{Symbol: symbol, Price: price, Timestamp: timestamp})
+	})
+
+	// Initialize rate limiting and API handlers
+	limiter := api.NewShardedLimiter(api.ShardedLimiterConfig{
+		RPS:             anonymousRPS,
+		Burst:           anonymousBurst,
+		MaxIdlePerShard: 1000,
+		ReapInterval:    5 * time.Minute,
+		ReapAfter:       30 * time.Minute,
+		Metrics:         metrics,
+	})
+
+	authManager := newAuthManager()
+	refreshTokenTTL := 30 * 24 * time.Hour
+	handler := api.New(db, authManager, refreshTokenTTL,
+		api.WithRateLimiter(limiter),
+		api.WithCacheMetrics(metrics),
+		api.WithBackfiller(backfiller),
+	)
 
 	// Set up Gin router
 	r := gin.Default()
 
+	// otelgin starts a span per request (and continues one from an inbound
+	// traceparent header); HTTPMetrics records latency once that request
+	// finishes, so it must come after.
+	r.Use(otelgin.Middleware("volatria-api"))
+	r.Use(api.HTTPMetrics(metrics))
+
 	// Configure CORS
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "X-User-ID", "X-API-Key", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "X-API-Key", "Authorization"},
 		ExposeHeaders:    []string{"Content-Length", "Content-Type"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
+	r.Use(limiter.Limit())
+
 	// Public routes
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
 	r.POST("/login", handler.Login)
+	r.POST("/auth/refresh", handler.RefreshToken)
+	r.POST("/auth/logout", handler.Logout)
 	r.GET("/stocks/:symbol", handler.GetLatestPrice)
 	r.GET("/stocks/:symbol/chart", handler.GetHistoricalPrices)
 	r.GET("/stocks", handler.GetPopularStocks)
+	r.GET("/ws/prices", stream.ServeWS(priceHub))
+	r.GET("/ws/stocks", stream.ServeWS(priceHub)) // alias kept for the frontend's existing stocks-tape client
+	r.GET("/sse/prices", stream.ServeSSE(priceHub))
 
 	// Protected routes
 	api := r.Group("/")
@@ -53,8 +158,49 @@ func main() {
 		api.GET("/watchlist", handler.GetWatchlist)
 	}
 
-	// Start server
-	if err := r.Run(":8080"); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	// Admin routes: symbol universe CRUD and on-demand backfill. There's no
+	// separate admin role yet, so these sit behind the same AuthMiddleware
+	// as the rest of the protected group rather than inventing one.
+	admin := r.Group("/admin")
+	admin.Use(handler.AuthMiddleware())
+	{
+		admin.GET("/symbols", handler.ListSymbols)
+		admin.POST("/symbols", handler.AddSymbol)
+		admin.DELETE("/symbols/:symbol", handler.RemoveSymbol)
+		admin.POST("/backfill", handler.EnqueueBackfill)
+	}
+
+	// Built explicitly (rather than r.Run) so shutdown can call
+	// server.Shutdown instead of leaving the listener to die with the
+	// process on signal.
+	server := &http.Server{
+		Addr:    ":8080",
+		Handler: r,
+	}
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stopSignals()
+	log.Println("shutdown signal received, draining in-flight requests")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("server shutdown: %v", err)
+	}
+	if err := stockFetcher.Stop(); err != nil {
+		log.Printf("fetcher stop: %v", err)
+	}
+	backfiller.Stop()
+	limiter.Stop()
+	if err := db.Close(); err != nil {
+		log.Printf("database close: %v", err)
 	}
+	shutdownTracer(context.Background())
 }